@@ -0,0 +1,72 @@
+package shim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// NewHttpRequestFromHttpApiV1Request creates an *http.Request from an HTTP API event using payload format version 1.0.
+// The event shares its shape with REST API events, but HTTP APIs comma-join repeated headers and query parameters in
+// the single value maps, so the multi value maps take precedence. The method and path fall back to the request context
+// when they are missing from the top level of the event.
+func NewHttpRequestFromHttpApiV1Request(ctx context.Context, event events.APIGatewayProxyRequest) (*http.Request, error) {
+	u, prefix := urlFromRestPath(event.Path, event.RequestContext.Path)
+	if event.Path == "" {
+		raw, err := urlFromRawPath(event.RequestContext.Path)
+		if err != nil {
+			return nil, fmt.Errorf("shim could not parse path from event: %w", err)
+		}
+		u, prefix = raw, ""
+	}
+
+	u.RawQuery = rawQueryFromEvent(event.QueryStringParameters, event.MultiValueQueryStringParameters)
+
+	method := event.HTTPMethod
+	if method == "" {
+		method = event.RequestContext.HTTPMethod
+	}
+
+	req, err := http.NewRequest(method, "/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("shim could not create http request from event: %w", err)
+	}
+	setRequestURL(req, u)
+	setProto(req, event.RequestContext.Protocol)
+
+	if err := setRequestBody(req, event.Body, event.IsBase64Encoded); err != nil {
+		return nil, fmt.Errorf("shim encountered an error while base64 decoding request body: %w", err)
+	}
+
+	for h, vs := range event.MultiValueHeaders {
+		for _, v := range vs {
+			req.Header.Add(h, v)
+		}
+	}
+
+	for h, v := range event.Headers {
+		if _, ok := req.Header[http.CanonicalHeaderKey(h)]; !ok {
+			req.Header.Set(h, v)
+		}
+	}
+
+	req.RemoteAddr = event.RequestContext.Identity.SourceIP
+	setRequestOrigin(req, event.RequestContext.DomainName)
+
+	if req.Header.Get(contentLength) == "" && req.ContentLength > 0 {
+		req.Header.Set(contentLength, strconv.FormatInt(req.ContentLength, 10))
+	}
+
+	ctx = contextWithPathPrefix(ctx, prefix)
+	ctx = context.WithValue(ctx, eventQueryContextKey{}, EventQuery{
+		QueryStringParameters:           event.QueryStringParameters,
+		MultiValueQueryStringParameters: event.MultiValueQueryStringParameters,
+	})
+	req = req.WithContext(ctx)
+	req = setRequestID(req, event.RequestContext.RequestID)
+
+	return req, nil
+}
@@ -0,0 +1,68 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestNewHttpRequestFromHttpApiV1RequestPrefersMultiValueMaps(t *testing.T) {
+	event := events.APIGatewayProxyRequest{
+		Path:       "/search",
+		HTTPMethod: http.MethodGet,
+		Headers: map[string]string{
+			"accept": "text/html,application/json",
+			"host":   "example.com",
+		},
+		MultiValueHeaders: map[string][]string{
+			"accept": {"text/html", "application/json"},
+		},
+		QueryStringParameters: map[string]string{
+			"tag": "a,b",
+		},
+		MultiValueQueryStringParameters: map[string][]string{
+			"tag": {"a", "b"},
+		},
+	}
+
+	req, err := NewHttpRequestFromHttpApiV1Request(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tags := req.URL.Query()["tag"]; len(tags) != 2 || tags[0] != "a" || tags[1] != "b" {
+		t.Errorf("expected tag to have values a and b but was %v", tags)
+	}
+
+	if accept := req.Header.Values("Accept"); len(accept) != 2 {
+		t.Errorf("expected two Accept values but was %v", accept)
+	}
+
+	if req.Host != "example.com" {
+		t.Errorf("expected host from single value headers but was %v", req.Host)
+	}
+}
+
+func TestNewHttpRequestFromHttpApiV1RequestFallsBackToRequestContext(t *testing.T) {
+	event := events.APIGatewayProxyRequest{
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod: http.MethodPut,
+			Path:       "/items/1",
+		},
+	}
+
+	req, err := NewHttpRequestFromHttpApiV1Request(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.Method != http.MethodPut {
+		t.Errorf("expected method PUT but was %v", req.Method)
+	}
+
+	if req.URL.Path != "/items/1" {
+		t.Errorf("expected path /items/1 but was %v", req.URL.Path)
+	}
+}
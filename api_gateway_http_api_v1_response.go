@@ -0,0 +1,37 @@
+package shim
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// NewHttpApiV1Response converts a shim.ResponseWriter into an events.APIGatewayProxyResponse for an HTTP API using payload
+// format version 1.0. Every header is sent through MultiValueHeaders so repeated headers such as Set-Cookie are kept
+// intact. HTTP APIs have no binary media type configuration, so any body that is encoded or not valid UTF-8 is base64
+// encoded.
+func NewHttpApiV1Response(rw *ResponseWriter) events.APIGatewayProxyResponse {
+	setContentTypeIfNotPresent(rw.Headers, rw.Body.Bytes())
+	setContentLength(rw)
+
+	headers := make(map[string][]string, len(rw.Headers))
+	for k, v := range rw.Headers {
+		canonicalKey := http.CanonicalHeaderKey(k)
+		headers[canonicalKey] = append(headers[canonicalKey], v...)
+	}
+
+	resp := events.APIGatewayProxyResponse{
+		StatusCode:        rw.Code,
+		MultiValueHeaders: headers,
+	}
+
+	bytes := rw.Body.Bytes()
+	if !httpApiBodyIsBase64(rw.Headers, bytes) {
+		resp.Body = string(bytes)
+	} else {
+		resp.Body = encodeBase64(bytes)
+		resp.IsBase64Encoded = true
+	}
+
+	return resp
+}
@@ -0,0 +1,80 @@
+package shim
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestNewHttpApiV1ResponseKeepsRepeatedHeaders(t *testing.T) {
+	rw := NewResponseWriter()
+	rw.Header().Add("Set-Cookie", "a=1; Expires=Wed, 21 Oct 2015 07:28:00 GMT")
+	rw.Header().Add("Set-Cookie", "b=2")
+	rw.Write([]byte("hello, world"))
+
+	resp := NewHttpApiV1Response(rw)
+
+	if cookies := resp.MultiValueHeaders["Set-Cookie"]; len(cookies) != 2 {
+		t.Errorf("expected two Set-Cookie values but was %v", cookies)
+	}
+
+	if resp.IsBase64Encoded || resp.Body != "hello, world" {
+		t.Errorf("expected plain text body but was %v", resp.Body)
+	}
+}
+
+func TestNewHttpApiV1ResponseBase64EncodesBinaryBodies(t *testing.T) {
+	body, err := gzipString("hello, world")
+	if err != nil {
+		t.Fatalf("unable to gzip string: %v", err)
+	}
+
+	rw := NewResponseWriter()
+	rw.Write(body)
+
+	resp := NewHttpApiV1Response(rw)
+
+	if !resp.IsBase64Encoded || resp.Body != base64.StdEncoding.EncodeToString(body) {
+		t.Error("expected body to be base64 encoded")
+	}
+}
+
+func TestHandleHttpApiRequestsAnyVersion(t *testing.T) {
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+
+	v1, _ := json.Marshal(map[string]interface{}{
+		"version":    "1.0",
+		"httpMethod": http.MethodPost,
+		"path":       "/v1",
+	})
+	resp, err := s.HandleHttpApiRequestsAnyVersion(context.Background(), v1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r, ok := resp.(events.APIGatewayProxyResponse); !ok || r.Body != "POST /v1" {
+		t.Errorf("expected a payload 1.0 response but was %+v", resp)
+	}
+
+	v2, _ := json.Marshal(events.APIGatewayV2HTTPRequest{
+		Version: "2.0",
+		RawPath: "/v2",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+	resp, err = s.HandleHttpApiRequestsAnyVersion(context.Background(), v2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r, ok := resp.(events.APIGatewayV2HTTPResponse); !ok || r.Body != "GET /v2" {
+		t.Errorf("expected a payload 2.0 response but was %+v", resp)
+	}
+}
@@ -2,12 +2,9 @@ package shim
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
 	"net/http"
-	"net/url"
 	"strconv"
-	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -18,67 +15,59 @@ const (
 
 var (
 	errDecodingBody              = errors.New("encountered an error while base64 decoding request body")
-	errCouldNotParsePath         = errors.New("could not parse path from event")
 	errCouldNotCreateHTTPRequest = errors.New("encountered error while create http request")
 )
 
 // NewHttpRequestFromAPIGatewayProxyRequest creates an *http.Request from a context.Context and an events.APIGatewayProxyRequest
 func NewHttpRequestFromAPIGatewayProxyRequest(ctx context.Context, event events.APIGatewayProxyRequest) (*http.Request, error) {
-	u, err := url.Parse(event.Path)
-	if err != nil {
-		return nil, errCouldNotParsePath
-	}
+	u, prefix := urlFromRestPath(event.Path, event.RequestContext.Path)
 
 	// Query parameters may or may not present, but if they are pull them out
 	// and encode them into the URL
-	if len(event.QueryStringParameters) > 0 {
-		queryParams := url.Values{}
-		for k, v := range event.QueryStringParameters {
-			queryParams.Add(k, v)
-		}
-		u.RawQuery = queryParams.Encode()
-	}
-
-	// Handle base64 encoding
-	body := event.Body
-	if event.IsBase64Encoded {
-		d, err := base64.StdEncoding.DecodeString(body)
-		if err != nil {
-			return nil, errDecodingBody
-		}
-
-		body = string(d)
-	}
+	u.RawQuery = rawQueryFromEvent(event.QueryStringParameters, event.MultiValueQueryStringParameters)
 
 	req, err := http.NewRequest(
 		event.HTTPMethod,
-		u.String(),
-		strings.NewReader(body),
+		"/",
+		nil,
 	)
 
 	if err != nil {
 		return nil, errCouldNotCreateHTTPRequest
 	}
+	setRequestURL(req, u)
+	setProto(req, event.RequestContext.Protocol)
+
+	// Handle base64 encoding, the body is decoded as the handler reads it
+	if err := setRequestBody(req, event.Body, event.IsBase64Encoded); err != nil {
+		return nil, errDecodingBody
+	}
 
 	// Pass along headers
 	for h, v := range event.Headers {
 		req.Header.Set(h, v)
 	}
 
-	// Set Host
-	req.URL.Host = req.Header.Get("Host")
-	req.Host = req.Header.Get("Host")
-
 	// Pass along remote IP
 	req.RemoteAddr = event.RequestContext.Identity.SourceIP
 
+	// Set scheme, host and TLS state
+	setRequestOrigin(req, event.RequestContext.DomainName)
+
 	// Ensure Content-Length is set correctly
-	if req.Header.Get(contentLength) == "" && body != "" {
-		req.Header.Set(contentLength, strconv.Itoa(len(body)))
+	if req.Header.Get(contentLength) == "" && req.ContentLength > 0 {
+		req.Header.Set(contentLength, strconv.FormatInt(req.ContentLength, 10))
 	}
 
-	// Pass along context to http.Handler
+	// Pass along context to http.Handler, with the query parameters as they arrived and the stage or base path the
+	// path was sent with for ExternalURL
+	ctx = contextWithPathPrefix(ctx, prefix)
+	ctx = context.WithValue(ctx, eventQueryContextKey{}, EventQuery{
+		QueryStringParameters:           event.QueryStringParameters,
+		MultiValueQueryStringParameters: event.MultiValueQueryStringParameters,
+	})
 	req = req.WithContext(ctx)
+	req = setRequestID(req, event.RequestContext.RequestID)
 
 	return req, nil
 }
@@ -1,7 +1,6 @@
 package shim
 
 import (
-	"encoding/base64"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -15,13 +14,25 @@ func NewAPIGatewayProxyResponse(rw *ResponseWriter) events.APIGatewayProxyRespon
 
 	httpHeaders := rw.Headers
 	setContentTypeIfNotPresent(httpHeaders, rw.Body.Bytes())
+	setContentLength(rw)
 
 	headers := formatHeaders(httpHeaders)
-	headers[httpHeaderContentType] = http.DetectContentType(rw.Body.Bytes())
+	// The Content-Type is sniffed from the body, except for compressed bodies where sniffing would only find the
+	// compression format
+	if !isContentEncoded(httpHeaders) {
+		headers[httpHeaderContentType] = http.DetectContentType(rw.Body.Bytes())
+	}
 	resp.Headers = headers
 
-	if shouldConvertToBase64(resp.Headers[httpHeaderContentType]) {
-		resp.Body = base64.StdEncoding.EncodeToString(rw.Body.Bytes())
+	// Set-Cookie values can't be joined with "," since cookie attributes like Expires contain commas, they are sent as
+	// separate headers through MultiValueHeaders instead
+	if cookies := httpHeaders.Values(httpHeaderSetCookie); len(cookies) > 0 {
+		delete(headers, httpHeaderSetCookie)
+		resp.MultiValueHeaders = map[string][]string{httpHeaderSetCookie: cookies}
+	}
+
+	if restBodyIsBase64(httpHeaders, rw.Body.Bytes()) {
+		resp.Body = encodeBase64(rw.Body.Bytes())
 		resp.IsBase64Encoded = true
 	} else {
 		resp.Body = string(rw.Body.String())
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestFormatHeadersCanonicalizesKeyNames(t *testing.T) {
@@ -117,3 +118,60 @@ func TestShouldConvertToBase64(t *testing.T) {
 		}
 	}
 }
+
+func TestNewAPIGatewayProxyResponseKeepsEverySetCookie(t *testing.T) {
+	rw := NewResponseWriter()
+	http.SetCookie(rw, &http.Cookie{Name: "session", Value: "abc", Expires: time.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC)})
+	http.SetCookie(rw, &http.Cookie{Name: "theme", Value: "dark", Path: "/"})
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.Write([]byte("ok"))
+
+	resp := NewAPIGatewayProxyResponse(rw)
+
+	cookies := resp.MultiValueHeaders["Set-Cookie"]
+	expected := []string{
+		"session=abc; Expires=Wed, 02 Jan 2030 03:04:05 GMT",
+		"theme=dark; Path=/",
+	}
+	if len(cookies) != len(expected) {
+		t.Fatalf("expected %v Set-Cookie values but got %q", len(expected), cookies)
+	}
+	for i := range expected {
+		if cookies[i] != expected[i] {
+			t.Errorf("expected Set-Cookie %q but got %q", expected[i], cookies[i])
+		}
+	}
+
+	if _, ok := resp.Headers["Set-Cookie"]; ok {
+		t.Errorf("expected Set-Cookie to be left out of the comma joined headers but got %q", resp.Headers["Set-Cookie"])
+	}
+	if resp.Headers["Content-Length"] != "2" {
+		t.Errorf("expected other headers to stay in Headers but got %+v", resp.Headers)
+	}
+}
+
+func TestNewAPIGatewayProxyResponseSniffsContentType(t *testing.T) {
+	rw := NewResponseWriter()
+	rw.Header().Set("Content-Type", "image/svg+xml")
+	rw.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`))
+
+	resp := NewAPIGatewayProxyResponse(rw)
+
+	if resp.IsBase64Encoded || resp.Body != `<svg xmlns="http://www.w3.org/2000/svg"></svg>` {
+		t.Errorf("expected a text body to be sent as text but got %+v", resp)
+	}
+	if resp.Headers["Content-Type"] != http.DetectContentType([]byte(resp.Body)) {
+		t.Errorf("expected the Content-Type to be sniffed from the body but got %v", resp.Headers["Content-Type"])
+	}
+
+	rw = NewResponseWriter()
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Encoding", "gzip")
+	rw.Write([]byte{0x1f, 0x8b, 0x08, 0x00})
+
+	resp = NewAPIGatewayProxyResponse(rw)
+
+	if !resp.IsBase64Encoded || resp.Headers["Content-Type"] != "application/json" {
+		t.Errorf("expected a compressed body to keep the handler's Content-Type but got %+v", resp)
+	}
+}
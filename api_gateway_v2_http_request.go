@@ -2,10 +2,8 @@ package shim
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 
@@ -14,7 +12,7 @@ import (
 
 // NewHttpRequestFromAPIGatewayV2HTTPRequest creates an *http.Request from the context passed from the Lambda library, and the event itself.
 func NewHttpRequestFromAPIGatewayV2HTTPRequest(ctx context.Context, event events.APIGatewayV2HTTPRequest) (*http.Request, error) {
-	u, err := url.Parse(event.RawPath)
+	u, err := urlFromRawPath(event.RawPath)
 	if err != nil {
 		return nil, fmt.Errorf("shim could not parse path from event: %w", err)
 	}
@@ -23,46 +21,42 @@ func NewHttpRequestFromAPIGatewayV2HTTPRequest(ctx context.Context, event events
 		u.RawQuery = event.RawQueryString
 	}
 
-	body := event.Body
-	if event.IsBase64Encoded {
-		d, err := base64.StdEncoding.DecodeString(body)
-		if err != nil {
-			return nil, fmt.Errorf("shim encountered an error while base64 decoding request body: %w", err)
-		}
-
-		body = string(d)
-	}
-
 	req, err := http.NewRequest(
 		event.RequestContext.HTTP.Method,
-		u.String(),
-		strings.NewReader(body),
+		"/",
+		nil,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("shim could not create http request from event: %w", err)
 	}
+	setRequestURL(req, u)
+	setProto(req, event.RequestContext.HTTP.Protocol)
+
+	if err := setRequestBody(req, event.Body, event.IsBase64Encoded); err != nil {
+		return nil, fmt.Errorf("shim encountered an error while base64 decoding request body: %w", err)
+	}
 
 	// Xray tracing is passed in via x-amzn-trace-id header that is on the Lambda Event
 	for h, v := range event.Headers {
 		req.Header.Set(h, v)
 	}
 
-	requestID := event.RequestContext.RequestID
-	if requestID != "" {
-		req.Header.Set("x-request-id", requestID)
+	// HTTP APIs move cookies out of the headers into their own field
+	if len(event.Cookies) > 0 {
+		req.Header.Set(httpHeaderCookie, strings.Join(event.Cookies, "; "))
 	}
 
-	req.URL.Host = req.Header.Get("Host")
-	req.Host = req.Header.Get("Host")
-
 	req.RemoteAddr = event.RequestContext.HTTP.SourceIP
+	setRequestOrigin(req, event.RequestContext.DomainName)
 
-	if req.Header.Get(contentLength) == "" && body != "" {
-		req.Header.Set(contentLength, strconv.Itoa(len(body)))
+	if req.Header.Get(contentLength) == "" && req.ContentLength > 0 {
+		req.Header.Set(contentLength, strconv.FormatInt(req.ContentLength, 10))
 	}
 
 	req = req.WithContext(ctx)
+	req = setRequestID(req, event.RequestContext.RequestID)
 
-	return req, nil
+	// Mutual TLS
+	return setClientCert(req, event.RequestContext.Authentication.ClientCert)
 }
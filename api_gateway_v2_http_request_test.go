@@ -121,3 +121,36 @@ func TestNewHttpRequestFromAPIGatewayV2HTTPRequest_XRayAndLambdaRequestIDs(t *te
 		t.Errorf("expected Lambda request ID '%s', got '%s'", requestID, req.Header.Get("Lambda-Runtime-Aws-Request-Id"))
 	}
 }
+
+func TestNewHttpRequestFromAPIGatewayV2HTTPRequest_Cookies(t *testing.T) {
+	event := events.APIGatewayV2HTTPRequest{
+		RawPath: "/",
+		Cookies: []string{
+			"session=abc",
+			`prefs="Expires=Wed, 02 Jan 2030 03:04:05 GMT"`,
+			"theme=dark",
+		},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	}
+
+	req, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := req.Cookie("session")
+	if err != nil || session.Value != "abc" {
+		t.Errorf("expected session cookie to be abc but got %v, %v", session, err)
+	}
+
+	prefs, err := req.Cookie("prefs")
+	if err != nil || prefs.Value != "Expires=Wed, 02 Jan 2030 03:04:05 GMT" {
+		t.Errorf("expected cookie value with a comma to be kept intact but got %v, %v", prefs, err)
+	}
+
+	if len(req.Cookies()) != 3 {
+		t.Errorf("expected 3 cookies but got %v", req.Cookies())
+	}
+}
@@ -1,9 +1,7 @@
 package shim
 
 import (
-	"encoding/base64"
 	"strings"
-	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -15,18 +13,20 @@ func NewApiGatewayV2HttpResponse(rw *ResponseWriter) events.APIGatewayV2HTTPResp
 
 	bytes := rw.Body.Bytes()
 
-	if utf8.Valid(bytes) {
+	if !httpApiBodyIsBase64(rw.Headers, bytes) {
 		output = string(bytes)
 	} else {
-		output = base64.StdEncoding.EncodeToString(bytes)
+		output = encodeBase64(bytes)
 		isBase64Encoded = true
 	}
 
-	headers := make(map[string]string)
-	cookies := make([]string, 0)
+	setContentLength(rw)
+
+	headers := make(map[string]string, len(rw.Headers))
+	cookies := make([]string, 0, len(rw.Headers[httpHeaderSetCookie]))
 
 	for key, values := range rw.Headers {
-		if key == "Set-Cookie" {
+		if key == httpHeaderSetCookie {
 			cookies = append(cookies, values...)
 		} else {
 			headers[key] = strings.Join(values, ",")
@@ -0,0 +1,121 @@
+package shim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	// WebsocketRoutePrefix is prepended to every WebSocket route key to build the synthetic path handed to the http.Handler
+	WebsocketRoutePrefix = "/_websocket/"
+
+	// WebsocketConnectPath is the path requests for the $connect route are sent to
+	WebsocketConnectPath = WebsocketRoutePrefix + "connect"
+	// WebsocketDisconnectPath is the path requests for the $disconnect route are sent to
+	WebsocketDisconnectPath = WebsocketRoutePrefix + "disconnect"
+	// WebsocketDefaultPath is the path requests for the $default route are sent to
+	WebsocketDefaultPath = WebsocketRoutePrefix + "default"
+)
+
+type websocketContextKey struct{}
+
+// WebsocketRoutePath returns the synthetic path for a WebSocket route key. The leading "$" of the predefined
+// route keys is dropped, e.g. "$connect" becomes "/_websocket/connect" and "sendMessage" becomes "/_websocket/sendMessage".
+func WebsocketRoutePath(routeKey string) string {
+	return WebsocketRoutePrefix + url.PathEscape(strings.TrimPrefix(routeKey, "$"))
+}
+
+// WebsocketRequestContext returns the request context of the WebSocket event that produced the request, if any.
+func WebsocketRequestContext(ctx context.Context) (events.APIGatewayWebsocketProxyRequestContext, bool) {
+	rc, ok := ctx.Value(websocketContextKey{}).(events.APIGatewayWebsocketProxyRequestContext)
+	return rc, ok
+}
+
+// WebsocketConnectionID returns the ID of the WebSocket connection that produced the request, if any.
+func WebsocketConnectionID(ctx context.Context) (string, bool) {
+	rc, ok := WebsocketRequestContext(ctx)
+	if !ok || rc.ConnectionID == "" {
+		return "", false
+	}
+
+	return rc.ConnectionID, true
+}
+
+// WebsocketCallbackURL returns the @connections management API endpoint for the API that produced the request,
+// e.g. https://abc123.execute-api.us-east-1.amazonaws.com/prod
+func WebsocketCallbackURL(ctx context.Context) (string, bool) {
+	rc, ok := WebsocketRequestContext(ctx)
+	if !ok || rc.DomainName == "" {
+		return "", false
+	}
+
+	u := url.URL{Scheme: "https", Host: rc.DomainName, Path: "/" + rc.Stage}
+	return u.String(), true
+}
+
+// NewHttpRequestFromAPIGatewayWebsocketProxyRequest creates an *http.Request from a context.Context and an
+// events.APIGatewayWebsocketProxyRequest. The request path is derived from the route key with WebsocketRoutePath and the
+// event's request context, including the connection ID, is available through WebsocketRequestContext.
+func NewHttpRequestFromAPIGatewayWebsocketProxyRequest(ctx context.Context, event events.APIGatewayWebsocketProxyRequest) (*http.Request, error) {
+	u, err := url.Parse(WebsocketRoutePath(event.RequestContext.RouteKey))
+	if err != nil {
+		return nil, fmt.Errorf("shim could not parse path from websocket event: %w", err)
+	}
+
+	if len(event.MultiValueQueryStringParameters) > 0 {
+		u.RawQuery = url.Values(event.MultiValueQueryStringParameters).Encode()
+	} else if len(event.QueryStringParameters) > 0 {
+		queryParams := url.Values{}
+		for k, v := range event.QueryStringParameters {
+			queryParams.Add(k, v)
+		}
+		u.RawQuery = queryParams.Encode()
+	}
+
+	// Only $connect carries an HTTP method, messages and $disconnect are modeled as POSTs
+	method := event.HTTPMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("shim could not create http request from websocket event: %w", err)
+	}
+
+	if err := setRequestBody(req, event.Body, event.IsBase64Encoded); err != nil {
+		return nil, fmt.Errorf("shim encountered an error while base64 decoding websocket message: %w", err)
+	}
+
+	for h, v := range event.Headers {
+		req.Header.Set(h, v)
+	}
+
+	for h, vs := range event.MultiValueHeaders {
+		req.Header.Del(h)
+		for _, v := range vs {
+			req.Header.Add(h, v)
+		}
+	}
+
+	req.URL.Host = req.Header.Get("Host")
+	req.Host = req.Header.Get("Host")
+
+	req.RemoteAddr = event.RequestContext.Identity.SourceIP
+
+	if req.Header.Get(contentLength) == "" && req.ContentLength > 0 {
+		req.Header.Set(contentLength, strconv.FormatInt(req.ContentLength, 10))
+	}
+
+	ctx = context.WithValue(ctx, websocketContextKey{}, event.RequestContext)
+	req = req.WithContext(ctx)
+	req = setRequestID(req, event.RequestContext.RequestID)
+
+	return req, nil
+}
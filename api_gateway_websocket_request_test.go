@@ -0,0 +1,125 @@
+package shim
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestWebsocketRoutePath(t *testing.T) {
+	cases := []struct {
+		in  string
+		out string
+	}{
+		{in: "$connect", out: WebsocketConnectPath},
+		{in: "$disconnect", out: WebsocketDisconnectPath},
+		{in: "$default", out: WebsocketDefaultPath},
+		{in: "sendMessage", out: "/_websocket/sendMessage"},
+	}
+
+	for _, c := range cases {
+		if out := WebsocketRoutePath(c.in); out != c.out {
+			t.Errorf("for %v expected %v but was %v", c.in, c.out, out)
+		}
+	}
+}
+
+func TestNewHttpRequestFromAPIGatewayWebsocketProxyRequest(t *testing.T) {
+	body := `{"action":"sendMessage"}`
+	event := events.APIGatewayWebsocketProxyRequest{
+		Body: body,
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+			RouteKey:     "$default",
+			ConnectionID: "abc123=",
+			DomainName:   "abc.execute-api.us-east-1.amazonaws.com",
+			Stage:        "prod",
+		},
+	}
+
+	req, err := NewHttpRequestFromAPIGatewayWebsocketProxyRequest(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.Method != http.MethodPost {
+		t.Errorf("expected method to be %v but was %v", http.MethodPost, req.Method)
+	}
+
+	if req.URL.Path != WebsocketDefaultPath {
+		t.Errorf("expected path to be %v but was %v", WebsocketDefaultPath, req.URL.Path)
+	}
+
+	reqBody, _ := io.ReadAll(req.Body)
+	if string(reqBody) != body {
+		t.Errorf("expected body to be %v but was %v", body, string(reqBody))
+	}
+
+	id, ok := WebsocketConnectionID(req.Context())
+	if !ok || id != "abc123=" {
+		t.Errorf("expected connection id to be abc123= but was %v", id)
+	}
+
+	callback, ok := WebsocketCallbackURL(req.Context())
+	if !ok || callback != "https://abc.execute-api.us-east-1.amazonaws.com/prod" {
+		t.Errorf("unexpected callback url %v", callback)
+	}
+}
+
+func TestHandleWebsocketRequestsRoutesLifecycleEvents(t *testing.T) {
+	conns := NewMemoryConnectionManager()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(WebsocketConnectPath, func(w http.ResponseWriter, r *http.Request) {
+		id, _ := WebsocketConnectionID(r.Context())
+		conns.Connect(id)
+	})
+	mux.HandleFunc(WebsocketDefaultPath, func(w http.ResponseWriter, r *http.Request) {
+		id, _ := WebsocketConnectionID(r.Context())
+		msg, _ := io.ReadAll(r.Body)
+		if err := conns.PostToConnection(r.Context(), id, msg); err != nil {
+			w.WriteHeader(http.StatusGone)
+		}
+	})
+	mux.HandleFunc(WebsocketDisconnectPath, func(w http.ResponseWriter, r *http.Request) {
+		id, _ := WebsocketConnectionID(r.Context())
+		conns.DeleteConnection(r.Context(), id)
+	})
+
+	s := New(mux)
+	send := func(routeKey, method, body string) events.APIGatewayProxyResponse {
+		resp, err := s.HandleWebsocketRequests(context.Background(), events.APIGatewayWebsocketProxyRequest{
+			HTTPMethod: method,
+			Body:       body,
+			RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+				RouteKey:     routeKey,
+				ConnectionID: "conn-1",
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error for %v: %v", routeKey, err)
+		}
+		return resp
+	}
+
+	send("$connect", http.MethodGet, "")
+	if resp := send("$default", "", "ping"); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code 200 but was %v", resp.StatusCode)
+	}
+
+	msgs := conns.Messages("conn-1")
+	if len(msgs) != 1 || string(msgs[0]) != "ping" {
+		t.Errorf("expected a single ping message but was %q", msgs)
+	}
+
+	send("$disconnect", "", "")
+	if conns.Connected("conn-1") {
+		t.Error("expected connection to be closed after $disconnect")
+	}
+
+	if resp := send("$default", "", "ping"); resp.StatusCode != http.StatusGone {
+		t.Errorf("expected status code 410 but was %v", resp.StatusCode)
+	}
+}
@@ -0,0 +1,248 @@
+package shim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	policyVersion      = "2012-10-17"
+	policyActionInvoke = "execute-api:Invoke"
+	policyEffectAllow  = "Allow"
+	policyEffectDeny   = "Deny"
+)
+
+var (
+	// ErrUnauthorized is returned by the REST authorizer handlers when the http.Handler responds with a 401. API Gateway
+	// turns an error with exactly this message into a 401 response for the client.
+	ErrUnauthorized = errors.New("Unauthorized")
+
+	errCouldNotParseMethodArn = errors.New("shim could not parse method arn from authorizer event")
+)
+
+type authorizationContextKey struct{}
+
+// Authorization collects the decision an http.Handler makes for a Lambda authorizer request. When neither Allow nor
+// Deny is called the response status decides: 2xx allows the request, 401 rejects it as unauthorized and anything else,
+// including a handler that writes nothing at all, denies it.
+//
+// The methods are safe to call on a nil *Authorization, so handlers can be shared with in-process middleware where
+// Authorizer returns nil.
+type Authorization struct {
+	PrincipalID        string
+	Context            map[string]interface{}
+	UsageIdentifierKey string
+
+	// Policy replaces the policy shim generates for the method ARN of the event. It is only used by REST authorizers.
+	Policy *events.APIGatewayCustomAuthorizerPolicy
+
+	decided bool
+	allowed bool
+}
+
+// Authorizer returns the Authorization for requests created from authorizer events and nil for any other request
+func Authorizer(r *http.Request) *Authorization {
+	a, _ := r.Context().Value(authorizationContextKey{}).(*Authorization)
+	return a
+}
+
+// Allow allows the request on behalf of principalID
+func (a *Authorization) Allow(principalID string) {
+	if a == nil {
+		return
+	}
+
+	a.decided = true
+	a.allowed = true
+	a.PrincipalID = principalID
+}
+
+// Deny denies the request
+func (a *Authorization) Deny() {
+	if a == nil {
+		return
+	}
+
+	a.decided = true
+	a.allowed = false
+}
+
+// Set adds a key to the context API Gateway passes on to the integration
+func (a *Authorization) Set(key string, value interface{}) {
+	if a == nil {
+		return
+	}
+
+	if a.Context == nil {
+		a.Context = make(map[string]interface{})
+	}
+	a.Context[key] = value
+}
+
+// allows reports the final decision, falling back to the response status code when no explicit decision was made
+func (a *Authorization) allows(code int) bool {
+	if a.decided {
+		return a.allowed
+	}
+
+	return isSuccess(code)
+}
+
+func withAuthorization(req *http.Request) (*http.Request, *Authorization) {
+	a := &Authorization{}
+	return req.WithContext(context.WithValue(req.Context(), authorizationContextKey{}, a)), a
+}
+
+// NewHttpRequestFromAPIGatewayCustomAuthorizerRequestTypeRequest creates an *http.Request from a REQUEST authorizer event.
+// The returned Authorization records the handler's decision.
+func NewHttpRequestFromAPIGatewayCustomAuthorizerRequestTypeRequest(ctx context.Context, event events.APIGatewayCustomAuthorizerRequestTypeRequest) (*http.Request, *Authorization, error) {
+	proxyEvent := events.APIGatewayProxyRequest{
+		Resource:                        event.Resource,
+		Path:                            event.Path,
+		HTTPMethod:                      event.HTTPMethod,
+		Headers:                         event.Headers,
+		MultiValueHeaders:               event.MultiValueHeaders,
+		QueryStringParameters:           event.QueryStringParameters,
+		MultiValueQueryStringParameters: event.MultiValueQueryStringParameters,
+		PathParameters:                  event.PathParameters,
+		StageVariables:                  event.StageVariables,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:    event.RequestContext.AccountID,
+			ResourceID:   event.RequestContext.ResourceID,
+			Stage:        event.RequestContext.Stage,
+			RequestID:    event.RequestContext.RequestID,
+			ResourcePath: event.RequestContext.ResourcePath,
+			HTTPMethod:   event.RequestContext.HTTPMethod,
+			APIID:        event.RequestContext.APIID,
+			Identity: events.APIGatewayRequestIdentity{
+				APIKey:   event.RequestContext.Identity.APIKey,
+				SourceIP: event.RequestContext.Identity.SourceIP,
+			},
+		},
+	}
+
+	req, err := NewHttpRequestFromAPIGatewayProxyRequest(ctx, proxyEvent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, a := withAuthorization(req)
+	return req, a, nil
+}
+
+// NewHttpRequestFromAPIGatewayCustomAuthorizerRequest creates an *http.Request from a TOKEN authorizer event. The method
+// and path are taken from the method ARN and the token is passed in the Authorization header.
+func NewHttpRequestFromAPIGatewayCustomAuthorizerRequest(ctx context.Context, event events.APIGatewayCustomAuthorizerRequest) (*http.Request, *Authorization, error) {
+	method, path, err := parseMethodArn(event.MethodArn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, path, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("shim could not create http request from token authorizer event: %w", err)
+	}
+
+	req.Header.Set("Authorization", event.AuthorizationToken)
+
+	req, a := withAuthorization(req)
+	return req, a, nil
+}
+
+// NewHttpRequestFromAPIGatewayV2CustomAuthorizerV2Request creates an *http.Request from an HTTP API authorizer event
+// using payload format 2.0. The returned Authorization records the handler's decision.
+func NewHttpRequestFromAPIGatewayV2CustomAuthorizerV2Request(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (*http.Request, *Authorization, error) {
+	httpEvent := events.APIGatewayV2HTTPRequest{
+		Version:               event.Version,
+		RouteKey:              event.RouteKey,
+		RawPath:               event.RawPath,
+		RawQueryString:        event.RawQueryString,
+		Cookies:               event.Cookies,
+		Headers:               event.Headers,
+		QueryStringParameters: event.QueryStringParameters,
+		PathParameters:        event.PathParameters,
+		RequestContext:        event.RequestContext,
+		StageVariables:        event.StageVariables,
+	}
+
+	req, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(ctx, httpEvent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, a := withAuthorization(req)
+	return req, a, nil
+}
+
+// NewAPIGatewayCustomAuthorizerResponse converts the handler's decision into an events.APIGatewayCustomAuthorizerResponse
+// with a policy for methodArn. ErrUnauthorized is returned if the handler responded with a 401 without deciding.
+func NewAPIGatewayCustomAuthorizerResponse(rw *ResponseWriter, a *Authorization, methodArn string) (events.APIGatewayCustomAuthorizerResponse, error) {
+	if !a.decided && rw.Code == http.StatusUnauthorized {
+		return events.APIGatewayCustomAuthorizerResponse{}, ErrUnauthorized
+	}
+
+	effect := policyEffectDeny
+	if a.allows(rw.Code) {
+		effect = policyEffectAllow
+	}
+
+	policy := events.APIGatewayCustomAuthorizerPolicy{
+		Version: policyVersion,
+		Statement: []events.IAMPolicyStatement{{
+			Action:   []string{policyActionInvoke},
+			Effect:   effect,
+			Resource: []string{methodArn},
+		}},
+	}
+	if a.Policy != nil {
+		policy = *a.Policy
+	}
+
+	return events.APIGatewayCustomAuthorizerResponse{
+		PrincipalID:        a.PrincipalID,
+		PolicyDocument:     policy,
+		Context:            a.Context,
+		UsageIdentifierKey: a.UsageIdentifierKey,
+	}, nil
+}
+
+// NewAPIGatewayV2CustomAuthorizerSimpleResponse converts the handler's decision into an
+// events.APIGatewayV2CustomAuthorizerSimpleResponse
+func NewAPIGatewayV2CustomAuthorizerSimpleResponse(rw *ResponseWriter, a *Authorization) events.APIGatewayV2CustomAuthorizerSimpleResponse {
+	return events.APIGatewayV2CustomAuthorizerSimpleResponse{
+		IsAuthorized: a.allows(rw.Code),
+		Context:      a.Context,
+	}
+}
+
+// parseMethodArn pulls the HTTP method and path out of a method ARN such as
+// arn:aws:execute-api:us-east-1:123456789012:abcdef123/prod/GET/pets/dog
+func parseMethodArn(arn string) (string, string, error) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 {
+		return "", "", errCouldNotParseMethodArn
+	}
+
+	// abcdef123/prod/GET/pets/dog
+	resource := strings.SplitN(parts[5], "/", 4)
+	if len(resource) < 3 {
+		return "", "", errCouldNotParseMethodArn
+	}
+
+	method := resource[2]
+	if method == "*" || method == "ANY" {
+		method = http.MethodGet
+	}
+
+	path := "/"
+	if len(resource) == 4 {
+		path += resource[3]
+	}
+
+	return method, path, nil
+}
@@ -0,0 +1,177 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const testMethodArn = "arn:aws:execute-api:us-east-1:123456789012:abcdef123/prod/GET/pets/dog"
+
+// authorizerHandler allows requests with the "Bearer good" token and rejects everything else by status code
+var authorizerHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	switch r.Header.Get("Authorization") {
+	case "Bearer good":
+		a := Authorizer(r)
+		a.Allow("user-1")
+		a.Set("tier", "gold")
+	case "":
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		w.WriteHeader(http.StatusForbidden)
+	}
+})
+
+func TestHandleAuthorizerRequests(t *testing.T) {
+	s := New(authorizerHandler)
+
+	resp, err := s.HandleAuthorizerRequests(context.Background(), events.APIGatewayCustomAuthorizerRequestTypeRequest{
+		Type:       "REQUEST",
+		MethodArn:  testMethodArn,
+		Path:       "/pets/dog",
+		HTTPMethod: http.MethodGet,
+		Headers:    map[string]string{"Authorization": "Bearer good"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.PrincipalID != "user-1" {
+		t.Errorf("expected principal id to be user-1 but was %v", resp.PrincipalID)
+	}
+
+	statement := resp.PolicyDocument.Statement[0]
+	if statement.Effect != policyEffectAllow || statement.Resource[0] != testMethodArn {
+		t.Errorf("expected allow policy for the method arn but was %+v", statement)
+	}
+
+	if resp.Context["tier"] != "gold" {
+		t.Errorf("expected context to be passed along but was %v", resp.Context)
+	}
+}
+
+func TestHandleAuthorizerRequestsFallsBackToStatusCode(t *testing.T) {
+	s := New(authorizerHandler)
+
+	resp, err := s.HandleAuthorizerRequests(context.Background(), events.APIGatewayCustomAuthorizerRequestTypeRequest{
+		MethodArn:  testMethodArn,
+		Path:       "/pets/dog",
+		HTTPMethod: http.MethodGet,
+		Headers:    map[string]string{"Authorization": "Bearer bad"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if effect := resp.PolicyDocument.Statement[0].Effect; effect != policyEffectDeny {
+		t.Errorf("expected deny policy but was %v", effect)
+	}
+
+	_, err = s.HandleAuthorizerRequests(context.Background(), events.APIGatewayCustomAuthorizerRequestTypeRequest{
+		MethodArn:  testMethodArn,
+		Path:       "/pets/dog",
+		HTTPMethod: http.MethodGet,
+	})
+	if err != ErrUnauthorized {
+		t.Errorf("expected ErrUnauthorized but was %v", err)
+	}
+}
+
+func TestHandleTokenAuthorizerRequests(t *testing.T) {
+	var method, path string
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		authorizerHandler(w, r)
+	}))
+
+	resp, err := s.HandleTokenAuthorizerRequests(context.Background(), events.APIGatewayCustomAuthorizerRequest{
+		Type:               "TOKEN",
+		AuthorizationToken: "Bearer good",
+		MethodArn:          testMethodArn,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if method != http.MethodGet || path != "/pets/dog" {
+		t.Errorf("expected GET /pets/dog from the method arn but was %v %v", method, path)
+	}
+
+	if resp.PolicyDocument.Statement[0].Effect != policyEffectAllow {
+		t.Errorf("expected allow policy but was %+v", resp.PolicyDocument)
+	}
+}
+
+func TestHandleHttpApiAuthorizerRequests(t *testing.T) {
+	s := New(authorizerHandler)
+
+	cases := []struct {
+		token      string
+		authorized bool
+	}{
+		{token: "Bearer good", authorized: true},
+		{token: "Bearer bad", authorized: false},
+		{token: "", authorized: false},
+	}
+
+	for _, c := range cases {
+		resp, err := s.HandleHttpApiAuthorizerRequests(context.Background(), events.APIGatewayV2CustomAuthorizerV2Request{
+			Version: "2.0",
+			Type:    "REQUEST",
+			RawPath: "/pets/dog",
+			Headers: map[string]string{"authorization": c.token},
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if resp.IsAuthorized != c.authorized {
+			t.Errorf("for %q expected IsAuthorized to be %v but was %v", c.token, c.authorized, resp.IsAuthorized)
+		}
+	}
+}
+
+func TestAuthorizationIsNilSafe(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	a := Authorizer(req)
+	if a != nil {
+		t.Fatal("expected no authorization outside of authorizer requests")
+	}
+
+	a.Allow("user-1")
+	a.Deny()
+	a.Set("key", "value")
+}
+
+func TestParseMethodArn(t *testing.T) {
+	cases := []struct {
+		arn    string
+		method string
+		path   string
+		err    bool
+	}{
+		{arn: testMethodArn, method: http.MethodGet, path: "/pets/dog"},
+		{arn: "arn:aws:execute-api:us-east-1:123456789012:abcdef123/prod/POST/", method: http.MethodPost, path: "/"},
+		{arn: "arn:aws:execute-api:us-east-1:123456789012:abcdef123/prod/ANY", method: http.MethodGet, path: "/"},
+		{arn: "not-an-arn", err: true},
+	}
+
+	for _, c := range cases {
+		method, path, err := parseMethodArn(c.arn)
+		if c.err {
+			if err == nil {
+				t.Errorf("expected error for %v", c.arn)
+			}
+			continue
+		}
+
+		if method != c.method || path != c.path {
+			t.Errorf("for %v expected %v %v but was %v %v", c.arn, c.method, c.path, method, path)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package shim
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// aws-lambda-go does not model Lambda@Edge events, the types below follow the event structure documented at
+// https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/lambda-event-structure.html
+
+const (
+	// CloudFrontBodyEncodingBase64 marks a CloudFront body as base64 encoded
+	CloudFrontBodyEncodingBase64 = "base64"
+	// CloudFrontBodyEncodingText marks a CloudFront body as plain text
+	CloudFrontBodyEncodingText = "text"
+)
+
+// CloudFrontEvent is the event Lambda@Edge sends for viewer-request and origin-request triggers
+type CloudFrontEvent struct {
+	Records []CloudFrontEventRecord `json:"Records"`
+}
+
+// CloudFrontEventRecord is a single record of a CloudFrontEvent. CloudFront always sends exactly one.
+type CloudFrontEventRecord struct {
+	CF CloudFrontRecord `json:"cf"`
+}
+
+// CloudFrontRecord holds the distribution config and the request that triggered the function
+type CloudFrontRecord struct {
+	Config  CloudFrontConfig  `json:"config"`
+	Request CloudFrontRequest `json:"request"`
+}
+
+// CloudFrontConfig describes the distribution and trigger that invoked the function
+type CloudFrontConfig struct {
+	DistributionDomainName string `json:"distributionDomainName"`
+	DistributionID         string `json:"distributionId"`
+	EventType              string `json:"eventType"`
+	RequestID              string `json:"requestId"`
+}
+
+// CloudFrontHeaders are keyed by the lowercase header name. Each entry keeps the header name as the viewer sent it.
+type CloudFrontHeaders map[string][]CloudFrontHeader
+
+// CloudFrontHeader is a single header value
+type CloudFrontHeader struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+// CloudFrontBody is the request body, present only when the trigger is configured to include it
+type CloudFrontBody struct {
+	InputTruncated bool   `json:"inputTruncated"`
+	Action         string `json:"action,omitempty"`
+	Encoding       string `json:"encoding"`
+	Data           string `json:"data"`
+}
+
+// CloudFrontRequest is the request as seen by CloudFront. Returning it from the function forwards it to the origin.
+type CloudFrontRequest struct {
+	ClientIP    string            `json:"clientIp"`
+	Headers     CloudFrontHeaders `json:"headers"`
+	Method      string            `json:"method"`
+	QueryString string            `json:"querystring"`
+	URI         string            `json:"uri"`
+	Body        *CloudFrontBody   `json:"body,omitempty"`
+	Origin      json.RawMessage   `json:"origin,omitempty"`
+}
+
+// CloudFrontResponse is a response generated at the edge. Returning it from the function answers the viewer directly.
+type CloudFrontResponse struct {
+	Status            string            `json:"status"`
+	StatusDescription string            `json:"statusDescription,omitempty"`
+	Headers           CloudFrontHeaders `json:"headers,omitempty"`
+	Body              string            `json:"body,omitempty"`
+	BodyEncoding      string            `json:"bodyEncoding,omitempty"`
+}
+
+// CloudFrontResult is returned from HandleCloudFrontRequests. Exactly one of Request or Response is set and it is
+// marshaled on its own, as CloudFront expects.
+type CloudFrontResult struct {
+	Request  *CloudFrontRequest
+	Response *CloudFrontResponse
+}
+
+// MarshalJSON adheres to the json.Marshaler interface
+func (r CloudFrontResult) MarshalJSON() ([]byte, error) {
+	if r.Request != nil {
+		return json.Marshal(r.Request)
+	}
+
+	if r.Response != nil {
+		return json.Marshal(r.Response)
+	}
+
+	return nil, errors.New("shim: cloudfront result has neither a request nor a response")
+}
@@ -0,0 +1,182 @@
+package shim
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var errNoCloudFrontRecord = errors.New("shim: cloudfront event has no records")
+
+type cloudFrontContextKey struct{}
+
+// cloudFrontState tracks the event a request was created from and whether the handler asked for it to be forwarded
+type cloudFrontState struct {
+	record    CloudFrontRecord
+	body      io.ReadCloser
+	continued *http.Request
+}
+
+// Continue tells shim to forward r to the origin instead of generating a response. r is usually the request passed to
+// the handler, possibly with a modified URL, method, headers or body. Anything written to the ResponseWriter is ignored.
+// Continue does nothing for requests that did not come from a CloudFront event.
+func Continue(r *http.Request) {
+	state, ok := r.Context().Value(cloudFrontContextKey{}).(*cloudFrontState)
+	if !ok {
+		return
+	}
+
+	state.continued = r
+}
+
+// CloudFrontConfigFromContext returns the distribution config of the CloudFront event that produced the request, if any.
+func CloudFrontConfigFromContext(ctx context.Context) (CloudFrontConfig, bool) {
+	state, ok := ctx.Value(cloudFrontContextKey{}).(*cloudFrontState)
+	if !ok {
+		return CloudFrontConfig{}, false
+	}
+
+	return state.record.Config, true
+}
+
+// CloudFrontBodyTruncated reports whether CloudFront truncated the body of r because it was larger than what is passed
+// to Lambda@Edge, 40KB for viewer requests and 1MB for origin requests. Handlers should not act on a truncated body as
+// if it were complete.
+func CloudFrontBodyTruncated(r *http.Request) bool {
+	state, ok := r.Context().Value(cloudFrontContextKey{}).(*cloudFrontState)
+	if !ok || state.record.Request.Body == nil {
+		return false
+	}
+
+	return state.record.Request.Body.InputTruncated
+}
+
+// NewHttpRequestFromCloudFrontEvent creates an *http.Request from a context.Context and the first record of a
+// CloudFrontEvent. The request can be passed to Continue to forward it to the origin.
+func NewHttpRequestFromCloudFrontEvent(ctx context.Context, event CloudFrontEvent) (*http.Request, error) {
+	if len(event.Records) == 0 {
+		return nil, errNoCloudFrontRecord
+	}
+
+	record := event.Records[0].CF
+	cfReq := record.Request
+
+	u, err := url.Parse(cfReq.URI)
+	if err != nil {
+		return nil, fmt.Errorf("shim could not parse uri from cloudfront event: %w", err)
+	}
+	u.RawQuery = cfReq.QueryString
+
+	var body []byte
+	if cfReq.Body != nil && cfReq.Body.Data != "" {
+		if cfReq.Body.Encoding == CloudFrontBodyEncodingBase64 {
+			body, err = base64.StdEncoding.DecodeString(cfReq.Body.Data)
+			if err != nil {
+				return nil, fmt.Errorf("shim encountered an error while base64 decoding cloudfront body: %w", err)
+			}
+		} else {
+			body = []byte(cfReq.Body.Data)
+		}
+	}
+
+	req, err := http.NewRequest(cfReq.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("shim could not create http request from cloudfront event: %w", err)
+	}
+
+	for name, values := range cfReq.Headers {
+		for _, h := range values {
+			// key is optional, the map key is the lowercase header name
+			key := h.Key
+			if key == "" {
+				key = name
+			}
+			req.Header.Add(key, h.Value)
+		}
+	}
+
+	req.URL.Host = req.Header.Get("Host")
+	req.Host = req.Header.Get("Host")
+
+	req.RemoteAddr = cfReq.ClientIP
+
+	state := &cloudFrontState{
+		record: record,
+		body:   req.Body,
+	}
+	req = req.WithContext(context.WithValue(ctx, cloudFrontContextKey{}, state))
+
+	return req, nil
+}
+
+// NewCloudFrontRequest converts an *http.Request created by NewHttpRequestFromCloudFrontEvent back into a
+// CloudFrontRequest. The body is only sent back to CloudFront if the handler replaced req.Body.
+func NewCloudFrontRequest(req *http.Request) (CloudFrontRequest, error) {
+	var original CloudFrontRequest
+	var originalBody io.ReadCloser
+	if state, ok := req.Context().Value(cloudFrontContextKey{}).(*cloudFrontState); ok {
+		original = state.record.Request
+		originalBody = state.body
+	}
+
+	cfReq := CloudFrontRequest{
+		ClientIP:    original.ClientIP,
+		Headers:     newCloudFrontHeaders(req.Header, original.Headers),
+		Method:      req.Method,
+		QueryString: req.URL.RawQuery,
+		URI:         req.URL.EscapedPath(),
+		Body:        original.Body,
+		Origin:      original.Origin,
+	}
+
+	// Host is lifted out of the header map by net/http, put it back so CloudFront keeps routing on it
+	if req.Host != "" {
+		key := "Host"
+		if hs, ok := original.Headers["host"]; ok && len(hs) > 0 && hs[0].Key != "" {
+			key = hs[0].Key
+		}
+		cfReq.Headers["host"] = []CloudFrontHeader{{Key: key, Value: req.Host}}
+	}
+
+	if req.Body != nil && req.Body != originalBody && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return CloudFrontRequest{}, fmt.Errorf("shim could not read replaced cloudfront request body: %w", err)
+		}
+
+		cfReq.Body = &CloudFrontBody{
+			Action:   "replace",
+			Encoding: CloudFrontBodyEncodingBase64,
+			Data:     encodeBase64(b),
+		}
+	}
+
+	return cfReq, nil
+}
+
+// newCloudFrontHeaders converts http.Header into CloudFront's lowercase multi-value format. Header names that were
+// present on the original event keep their original spelling.
+func newCloudFrontHeaders(h http.Header, original CloudFrontHeaders) CloudFrontHeaders {
+	headers := make(CloudFrontHeaders, len(h))
+
+	for k, values := range h {
+		lower := strings.ToLower(k)
+
+		key := k
+		if hs, ok := original[lower]; ok && len(hs) > 0 && hs[0].Key != "" {
+			key = hs[0].Key
+		}
+
+		for _, v := range values {
+			headers[lower] = append(headers[lower], CloudFrontHeader{Key: key, Value: v})
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,201 @@
+package shim
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newTestCloudFrontEvent() CloudFrontEvent {
+	return CloudFrontEvent{
+		Records: []CloudFrontEventRecord{{
+			CF: CloudFrontRecord{
+				Config: CloudFrontConfig{EventType: "origin-request", DistributionID: "EDFDVBD6EXAMPLE"},
+				Request: CloudFrontRequest{
+					ClientIP:    "203.0.113.178",
+					Method:      http.MethodPost,
+					URI:         "/images/a%20b.png",
+					QueryString: "size=large&b=2",
+					Headers: CloudFrontHeaders{
+						"host":       {{Key: "Host", Value: "d111111abcdef8.cloudfront.net"}},
+						"user-agent": {{Key: "User-Agent", Value: "curl/7.66.0"}},
+						"x-multi":    {{Key: "X-MULTI", Value: "one"}, {Key: "X-MULTI", Value: "two"}},
+					},
+					Body: &CloudFrontBody{
+						Encoding: CloudFrontBodyEncodingBase64,
+						Data:     base64.StdEncoding.EncodeToString([]byte("hello, world")),
+					},
+				},
+			},
+		}},
+	}
+}
+
+func TestNewHttpRequestFromCloudFrontEvent(t *testing.T) {
+	req, err := NewHttpRequestFromCloudFrontEvent(context.Background(), newTestCloudFrontEvent())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.URL.Path != "/images/a b.png" {
+		t.Errorf("expected decoded path but was %v", req.URL.Path)
+	}
+
+	if req.URL.Query().Get("size") != "large" {
+		t.Errorf("expected size query param to be large but was %v", req.URL.Query().Get("size"))
+	}
+
+	if req.Host != "d111111abcdef8.cloudfront.net" {
+		t.Errorf("expected host to be set from headers but was %v", req.Host)
+	}
+
+	if vals := req.Header.Values("X-Multi"); len(vals) != 2 {
+		t.Errorf("expected two values for X-Multi but was %v", vals)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != "hello, world" {
+		t.Errorf("expected body to be decoded but was %v", string(body))
+	}
+
+	if config, ok := CloudFrontConfigFromContext(req.Context()); !ok || config.EventType != "origin-request" {
+		t.Errorf("expected config to be available from context but was %+v", config)
+	}
+}
+
+func TestNewHttpRequestFromCloudFrontEventWithoutHeaderKeys(t *testing.T) {
+	event := newTestCloudFrontEvent()
+	event.Records[0].CF.Request.Headers = CloudFrontHeaders{
+		"host":     {{Value: "d111111abcdef8.cloudfront.net"}},
+		"x-custom": {{Value: "v"}},
+	}
+
+	req, err := NewHttpRequestFromCloudFrontEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := req.Header[""]; ok {
+		t.Errorf("expected no header with an empty name but got %v", req.Header)
+	}
+	if req.Header.Get("X-Custom") != "v" || req.Host != "d111111abcdef8.cloudfront.net" {
+		t.Errorf("expected headers without a key to fall back to the map key but got %v", req.Header)
+	}
+
+	cfReq, err := NewCloudFrontRequest(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if host := cfReq.Headers["host"]; len(host) != 1 || host[0].Key != "Host" {
+		t.Errorf("expected the forwarded Host header to have a key but got %+v", host)
+	}
+}
+
+func TestCloudFrontBodyTruncated(t *testing.T) {
+	event := newTestCloudFrontEvent()
+
+	req, err := NewHttpRequestFromCloudFrontEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if CloudFrontBodyTruncated(req) {
+		t.Error("expected a complete body not to be reported as truncated")
+	}
+
+	event.Records[0].CF.Request.Body.InputTruncated = true
+	req, err = NewHttpRequestFromCloudFrontEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !CloudFrontBodyTruncated(req) {
+		t.Error("expected a truncated body to be reported")
+	}
+
+	plain, _ := http.NewRequest(http.MethodGet, "/", nil)
+	if CloudFrontBodyTruncated(plain) {
+		t.Error("expected requests that didn't come from CloudFront not to be truncated")
+	}
+}
+
+func TestNewHttpRequestFromCloudFrontEventRequiresARecord(t *testing.T) {
+	if _, err := NewHttpRequestFromCloudFrontEvent(context.Background(), CloudFrontEvent{}); err == nil {
+		t.Error("expected error for event without records")
+	}
+}
+
+func TestHandleCloudFrontRequestsContinue(t *testing.T) {
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = "/rewritten.png"
+		r.Header.Set("X-Rewritten", "true")
+		Continue(r)
+	}))
+
+	result, err := s.HandleCloudFrontRequests(context.Background(), newTestCloudFrontEvent())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Response != nil || result.Request == nil {
+		t.Fatalf("expected the request to be forwarded but was %+v", result)
+	}
+
+	cfReq := result.Request
+	if cfReq.URI != "/rewritten.png" {
+		t.Errorf("expected uri to be rewritten but was %v", cfReq.URI)
+	}
+
+	if cfReq.QueryString != "size=large&b=2" {
+		t.Errorf("expected query string to be preserved but was %v", cfReq.QueryString)
+	}
+
+	if hs := cfReq.Headers["x-rewritten"]; len(hs) != 1 || hs[0].Value != "true" {
+		t.Errorf("expected x-rewritten header but was %+v", hs)
+	}
+
+	if hs := cfReq.Headers["x-multi"]; len(hs) != 2 || hs[0].Key != "X-MULTI" {
+		t.Errorf("expected original header spelling to be kept but was %+v", hs)
+	}
+
+	if hs := cfReq.Headers["host"]; len(hs) != 1 || hs[0].Value != "d111111abcdef8.cloudfront.net" {
+		t.Errorf("expected host header to be kept but was %+v", hs)
+	}
+
+	if cfReq.Body == nil || cfReq.Body.Action != "" {
+		t.Errorf("expected untouched body to be passed through but was %+v", cfReq.Body)
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling result: %v", err)
+	}
+
+	if !strings.Contains(string(out), `"uri":"/rewritten.png"`) {
+		t.Errorf("expected result to marshal as the request but was %s", out)
+	}
+}
+
+func TestHandleCloudFrontRequestsContinueWithReplacedBody(t *testing.T) {
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = io.NopCloser(strings.NewReader("goodbye, world"))
+		Continue(r)
+	}))
+
+	result, err := s.HandleCloudFrontRequests(context.Background(), newTestCloudFrontEvent())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := result.Request.Body
+	if body == nil || body.Action != "replace" {
+		t.Fatalf("expected body to be replaced but was %+v", body)
+	}
+
+	data, _ := base64.StdEncoding.DecodeString(body.Data)
+	if string(data) != "goodbye, world" {
+		t.Errorf("expected replaced body but was %v", string(data))
+	}
+}
@@ -0,0 +1,36 @@
+package shim
+
+import (
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+// NewCloudFrontResponse converts a shim.ResponseWriter into a CloudFrontResponse generated at the edge
+func NewCloudFrontResponse(rw *ResponseWriter) CloudFrontResponse {
+	code := rw.Code
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	resp := CloudFrontResponse{
+		Status:            strconv.Itoa(code),
+		StatusDescription: http.StatusText(code),
+		Headers:           newCloudFrontHeaders(rw.Headers, nil),
+	}
+
+	bytes := rw.Body.Bytes()
+	if len(bytes) == 0 {
+		return resp
+	}
+
+	if utf8.Valid(bytes) {
+		resp.Body = string(bytes)
+		resp.BodyEncoding = CloudFrontBodyEncodingText
+	} else {
+		resp.Body = encodeBase64(bytes)
+		resp.BodyEncoding = CloudFrontBodyEncodingBase64
+	}
+
+	return resp
+}
@@ -0,0 +1,84 @@
+package shim
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewCloudFrontResponse(t *testing.T) {
+	rw := NewResponseWriter()
+	rw.Header().Set("Cache-Control", "max-age=60")
+	rw.Header().Add("Set-Cookie", "a=1")
+	rw.Header().Add("Set-Cookie", "b=2")
+	rw.WriteHeader(http.StatusForbidden)
+	rw.Write([]byte("denied"))
+
+	resp := NewCloudFrontResponse(rw)
+
+	if resp.Status != "403" || resp.StatusDescription != "Forbidden" {
+		t.Errorf("unexpected status %v %v", resp.Status, resp.StatusDescription)
+	}
+
+	if hs := resp.Headers["cache-control"]; len(hs) != 1 || hs[0].Key != "Cache-Control" {
+		t.Errorf("expected lowercase header key with canonical name but was %+v", resp.Headers)
+	}
+
+	if hs := resp.Headers["set-cookie"]; len(hs) != 2 {
+		t.Errorf("expected each cookie to be its own value but was %+v", hs)
+	}
+
+	if resp.Body != "denied" || resp.BodyEncoding != CloudFrontBodyEncodingText {
+		t.Errorf("expected text body but was %v (%v)", resp.Body, resp.BodyEncoding)
+	}
+}
+
+func TestNewCloudFrontResponseBase64EncodesBinaryBodies(t *testing.T) {
+	body, err := gzipString("hello, world")
+	if err != nil {
+		t.Fatalf("unable to gzip string: %v", err)
+	}
+
+	rw := NewResponseWriter()
+	rw.Write(body)
+	resp := NewCloudFrontResponse(rw)
+
+	if resp.BodyEncoding != CloudFrontBodyEncodingBase64 {
+		t.Fatalf("expected base64 body encoding but was %v", resp.BodyEncoding)
+	}
+
+	if resp.Body != base64.StdEncoding.EncodeToString(body) {
+		t.Error("expected body to be base64 encoded")
+	}
+}
+
+func TestHandleCloudFrontRequestsGeneratesResponse(t *testing.T) {
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com/", http.StatusFound)
+	}))
+
+	result, err := s.HandleCloudFrontRequests(context.Background(), newTestCloudFrontEvent())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Request != nil || result.Response == nil {
+		t.Fatalf("expected a generated response but was %+v", result)
+	}
+
+	if result.Response.Status != "302" {
+		t.Errorf("expected status 302 but was %v", result.Response.Status)
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling result: %v", err)
+	}
+
+	if !strings.Contains(string(out), `"status":"302"`) {
+		t.Errorf("expected result to marshal as the response but was %s", out)
+	}
+}
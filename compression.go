@@ -0,0 +1,212 @@
+package shim
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultCompressionMinSize is the body size in bytes below which responses are not compressed
+	DefaultCompressionMinSize = 1024
+
+	httpHeaderAcceptEncoding  = "Accept-Encoding"
+	httpHeaderContentEncoding = "Content-Encoding"
+	httpHeaderVary            = "Vary"
+)
+
+var (
+	// GzipCompressor compresses responses with gzip at the default compression level
+	GzipCompressor = NewCompressor("gzip", func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	})
+
+	// DeflateCompressor compresses responses with deflate at the default compression level
+	DeflateCompressor = NewCompressor("deflate", func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	})
+)
+
+// Compressor compresses response bodies for a single Content-Encoding
+type Compressor interface {
+	Encoding() string
+	Compress(dst io.Writer, src []byte) error
+}
+
+// NewCompressor returns a Compressor for encoding backed by the io.WriteCloser newWriter returns. It adapts most
+// compression libraries, e.g. brotli:
+//
+//	shim.NewCompressor("br", func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) })
+func NewCompressor(encoding string, newWriter func(w io.Writer) io.WriteCloser) Compressor {
+	return writerCompressor{encoding: encoding, newWriter: newWriter}
+}
+
+type writerCompressor struct {
+	encoding  string
+	newWriter func(w io.Writer) io.WriteCloser
+}
+
+func (c writerCompressor) Encoding() string {
+	return c.encoding
+}
+
+func (c writerCompressor) Compress(dst io.Writer, src []byte) error {
+	w := c.newWriter(dst)
+	if _, err := w.Write(src); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// Compression configures how response bodies are compressed
+type Compression struct {
+	// MinSize is the body size in bytes below which responses are not compressed
+	MinSize int
+	// Compressors in order of preference when the client accepts several encodings equally
+	Compressors []Compressor
+}
+
+// WithCompression is an option function that compresses response bodies of at least minSize bytes when the client's
+// Accept-Encoding allows it and the Content-Type is compressible. Compressed responses are always base64 encoded.
+// Compressors are listed in order of preference and default to gzip.
+func WithCompression(minSize int, compressors ...Compressor) func(*Shim) {
+	if len(compressors) == 0 {
+		compressors = []Compressor{GzipCompressor}
+	}
+
+	return func(s *Shim) {
+		s.Compression = &Compression{
+			MinSize:     minSize,
+			Compressors: compressors,
+		}
+	}
+}
+
+// compress compresses the body of rw in place if the request and response allow it
+func (c *Compression) compress(req *http.Request, rw *ResponseWriter) error {
+	body := rw.Body.Bytes()
+	if len(body) < c.MinSize || rw.Code == http.StatusNoContent || rw.Code == http.StatusNotModified {
+		return nil
+	}
+
+	if rw.Headers.Get(httpHeaderContentEncoding) != "" || !isCompressible(rw.Headers.Get(httpHeaderContentType)) {
+		return nil
+	}
+
+	if strings.Contains(rw.Headers.Get("Cache-Control"), "no-transform") {
+		return nil
+	}
+
+	// The response differs by Accept-Encoding whether or not this particular client gets it compressed
+	addVary(rw.Headers, httpHeaderAcceptEncoding)
+
+	compressor := negotiateCompressor(req.Header.Values(httpHeaderAcceptEncoding), c.Compressors)
+	if compressor == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := compressor.Compress(&buf, body); err != nil {
+		return err
+	}
+
+	if buf.Len() >= len(body) {
+		return nil
+	}
+
+	rw.Body.Reset()
+	rw.Body.Write(buf.Bytes())
+	rw.Headers.Set(httpHeaderContentEncoding, compressor.Encoding())
+	rw.Headers.Del(contentLength)
+
+	// The compressed body is no longer byte for byte what a strong ETag promises
+	if etag := rw.Headers.Get(httpHeaderETag); etag != "" && !strings.HasPrefix(etag, "W/") {
+		rw.Headers.Set(httpHeaderETag, "W/"+etag)
+	}
+
+	return nil
+}
+
+// negotiateCompressor picks the compressor with the highest q-value in the Accept-Encoding header values. Ties are
+// broken by the order of compressors.
+func negotiateCompressor(acceptEncoding []string, compressors []Compressor) Compressor {
+	accepted := make(map[string]float64)
+	for _, header := range acceptEncoding {
+		for _, part := range strings.Split(header, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			if coding == "" {
+				continue
+			}
+
+			q := 1.0
+			if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+			accepted[strings.ToLower(coding)] = q
+		}
+	}
+
+	var best Compressor
+	bestQ := 0.0
+	for _, c := range compressors {
+		q, ok := accepted[c.Encoding()]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+
+		if ok && q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+
+	return best
+}
+
+// isCompressible reports whether a Content-Type is worth compressing. Images, video and archives are already compressed.
+func isCompressible(ct string) bool {
+	mimeType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasPrefix(mimeType, prefixText) || strings.HasSuffix(mimeType, "+json") || strings.HasSuffix(mimeType, "+xml") {
+		return true
+	}
+
+	for _, t := range textFormats {
+		if t == mimeType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addVary adds value to the Vary header unless it is already present
+func addVary(h http.Header, value string) {
+	for _, v := range h.Values(httpHeaderVary) {
+		for _, existing := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), value) {
+				return
+			}
+		}
+	}
+
+	h.Add(httpHeaderVary, value)
+}
+
+// isContentEncoded reports whether the response body has been encoded, e.g. compressed, and must be sent as binary
+func isContentEncoded(h http.Header) bool {
+	ce := h.Get(httpHeaderContentEncoding)
+	return ce != "" && !strings.EqualFold(ce, "identity")
+}
@@ -0,0 +1,164 @@
+package shim
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var largeJSON = `{"items":[` + strings.Repeat(`{"name":"item","price":100},`, 100) + `{}]}`
+
+func jsonHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(httpHeaderContentType, "application/json")
+	w.Write([]byte(largeJSON))
+}
+
+func TestHandleCompressesResponses(t *testing.T) {
+	s := New(http.HandlerFunc(jsonHandler), WithCompression(DefaultCompressionMinSize))
+
+	resp, err := s.Handle(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/",
+		Headers:    map[string]string{"Accept-Encoding": "br;q=1.0, gzip;q=0.8"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Headers[httpHeaderContentEncoding] != "gzip" {
+		t.Fatalf("expected gzip content encoding but was %v", resp.Headers[httpHeaderContentEncoding])
+	}
+
+	if resp.Headers[httpHeaderVary] != httpHeaderAcceptEncoding {
+		t.Errorf("expected Vary to be Accept-Encoding but was %v", resp.Headers[httpHeaderVary])
+	}
+
+	if resp.Headers[httpHeaderContentType] != "application/json" {
+		t.Errorf("expected content type to be kept but was %v", resp.Headers[httpHeaderContentType])
+	}
+
+	if !resp.IsBase64Encoded {
+		t.Fatal("expected compressed response to be base64 encoded")
+	}
+
+	decoded, _ := base64.StdEncoding.DecodeString(resp.Body)
+	body, err := gunzipBytes(decoded)
+	if err != nil {
+		t.Fatalf("unable to gunzip body: %v", err)
+	}
+
+	if body != largeJSON {
+		t.Error("expected decompressed body to match the handler's body")
+	}
+}
+
+func TestHandleHttpApiRequestsCompressesWithPreferredCompressor(t *testing.T) {
+	s := New(http.HandlerFunc(jsonHandler), WithCompression(0, DeflateCompressor, GzipCompressor))
+
+	resp, err := s.HandleHttpApiRequests(context.Background(), events.APIGatewayV2HTTPRequest{
+		RawPath: "/",
+		Headers: map[string]string{"accept-encoding": "gzip, deflate"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Headers[httpHeaderContentEncoding] != "deflate" {
+		t.Fatalf("expected deflate content encoding but was %v", resp.Headers[httpHeaderContentEncoding])
+	}
+
+	decoded, _ := base64.StdEncoding.DecodeString(resp.Body)
+	body, _ := io.ReadAll(flate.NewReader(bytes.NewReader(decoded)))
+	if string(body) != largeJSON || !resp.IsBase64Encoded {
+		t.Error("expected base64 encoded deflated body")
+	}
+}
+
+func TestCompressionSkipsResponses(t *testing.T) {
+	cases := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+	}{
+		{name: "no accept-encoding", contentType: "application/json", body: largeJSON},
+		{name: "refused encoding", acceptEncoding: "gzip;q=0", contentType: "application/json", body: largeJSON},
+		{name: "small body", acceptEncoding: "gzip", contentType: "application/json", body: "{}"},
+		{name: "incompressible type", acceptEncoding: "gzip", contentType: "image/png", body: largeJSON},
+	}
+
+	c := &Compression{MinSize: DefaultCompressionMinSize, Compressors: []Compressor{GzipCompressor}}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if tc.acceptEncoding != "" {
+			req.Header.Set(httpHeaderAcceptEncoding, tc.acceptEncoding)
+		}
+
+		rw := NewResponseWriter()
+		rw.Header().Set(httpHeaderContentType, tc.contentType)
+		rw.Write([]byte(tc.body))
+
+		if err := c.compress(req, rw); err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.name, err)
+		}
+
+		if rw.Headers.Get(httpHeaderContentEncoding) != "" || rw.Body.String() != tc.body {
+			t.Errorf("%v: expected response to be left uncompressed", tc.name)
+		}
+	}
+}
+
+func TestNegotiateCompressor(t *testing.T) {
+	compressors := []Compressor{GzipCompressor, DeflateCompressor}
+	cases := []struct {
+		in  string
+		out string
+	}{
+		{in: "gzip, deflate", out: "gzip"},
+		{in: "gzip;q=0.5, deflate", out: "deflate"},
+		{in: "*", out: "gzip"},
+		{in: "*;q=0.1, gzip;q=0", out: "deflate"},
+		{in: "identity", out: ""},
+	}
+
+	for _, c := range cases {
+		var out string
+		if compressor := negotiateCompressor([]string{c.in}, compressors); compressor != nil {
+			out = compressor.Encoding()
+		}
+
+		if out != c.out {
+			t.Errorf("for %v expected %q but was %q", c.in, c.out, out)
+		}
+	}
+}
+
+func TestIsCompressible(t *testing.T) {
+	cases := []struct {
+		in  string
+		out bool
+	}{
+		{in: "text/html; charset=utf-8", out: true},
+		{in: "application/json", out: true},
+		{in: "application/problem+json", out: true},
+		{in: "image/svg+xml", out: true},
+		{in: "image/png", out: false},
+		{in: "", out: false},
+	}
+
+	for _, c := range cases {
+		if out := isCompressible(c.in); out != c.out {
+			t.Errorf("for %v expected %v but was %v", c.in, c.out, out)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package shim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	httpHeaderETag        = "ETag"
+	httpHeaderIfNoneMatch = "If-None-Match"
+)
+
+// WithAutoETag is an option function that sets a strong ETag on successful GET and HEAD responses that don't have one,
+// derived from a hash of the body, and answers requests whose If-None-Match matches the ETag with a 304 Not Modified
+func WithAutoETag() func(*Shim) {
+	return func(s *Shim) {
+		s.AutoETag = true
+	}
+}
+
+// applyETag sets the ETag if AutoETag is enabled and turns the response into a 304 if the client already has it. ETags
+// set by the handler are honored as well. No ETag is generated for HEAD responses whose handler didn't write the body.
+func (s *Shim) applyETag(req *http.Request, rw *ResponseWriter) {
+	if !s.AutoETag || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return
+	}
+
+	if rw.Code != 0 && rw.Code != http.StatusOK {
+		return
+	}
+
+	etag := rw.Headers.Get(httpHeaderETag)
+	if etag == "" {
+		// Handlers like http.ServeContent skip the body for HEAD, so its hash would not be the ETag of the GET response
+		if req.Method == http.MethodHead && rw.Body.Len() == 0 {
+			return
+		}
+
+		etag = bodyETag(rw.Body.Bytes())
+		rw.Headers.Set(httpHeaderETag, etag)
+	}
+
+	if !etagMatches(strings.Join(req.Header.Values(httpHeaderIfNoneMatch), ","), etag) {
+		return
+	}
+
+	s.printf(req.Context(), "etag %v matched If-None-Match, responding with 304\n", etag)
+
+	// Same headers net/http drops from a 304 in http.ServeContent
+	rw.Headers.Del(httpHeaderContentType)
+	rw.Headers.Del(contentLength)
+	rw.Headers.Del(httpHeaderContentEncoding)
+	rw.Headers.Del("Last-Modified")
+	rw.Code = http.StatusNotModified
+}
+
+// bodyETag returns a strong ETag for body
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header value matches etag using the weak comparison RFC 9110 requires
+// for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	for {
+		ifNoneMatch = strings.TrimLeft(ifNoneMatch, " \t,")
+		if ifNoneMatch == "" {
+			return false
+		}
+
+		tag, rest, ok := scanETag(ifNoneMatch)
+		if !ok {
+			return false
+		}
+
+		if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+
+		ifNoneMatch = rest
+	}
+}
+
+// scanETag splits the entity tag at the start of s from the rest of s. Entity tags may contain commas, so they can't
+// simply be split on them.
+func scanETag(s string) (tag, rest string, ok bool) {
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+
+	if len(s)-start < 2 || s[start] != '"' {
+		return "", "", false
+	}
+
+	end := strings.IndexByte(s[start+1:], '"')
+	if end < 0 {
+		return "", "", false
+	}
+	end += start + 2
+
+	return s[:end], s[end:], true
+}
+
+// stripBody removes the body from responses that must not have one, HEAD responses and 1xx, 204 and 304 responses. HEAD
+// responses keep the Content-Length of the body they would have had. A missing status code becomes 200 like it does
+// in net/http, so handlers that only set headers, e.g. for OPTIONS requests, still produce a valid response.
+func stripBody(req *http.Request, rw *ResponseWriter) {
+	if rw.Code == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	switch {
+	case rw.Code < 200 || rw.Code == http.StatusNoContent:
+		rw.Headers.Del(contentLength)
+	case rw.Code == http.StatusNotModified:
+	case req.Method == http.MethodHead:
+		if rw.Headers.Get(contentLength) == "" && rw.Body.Len() > 0 {
+			rw.Headers.Set(contentLength, strconv.Itoa(rw.Body.Len()))
+		}
+	default:
+		return
+	}
+
+	rw.Body.Reset()
+	rw.jsonOverhead = 0
+}
@@ -0,0 +1,195 @@
+package shim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var conditionalHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/empty":
+		w.WriteHeader(http.StatusNoContent)
+		fmt.Fprint(w, "ignored")
+	case "/options":
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+	case "/tagged":
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "tagged")
+	default:
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "hello, world")
+	}
+})
+
+func TestHeadRequestsHaveNoBody(t *testing.T) {
+	s := New(conditionalHandler)
+
+	resp, err := s.Handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodHead, Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Body != "" {
+		t.Errorf("expected HEAD response to have no body but got %q", resp.Body)
+	}
+	if resp.Headers["Content-Length"] != "12" {
+		t.Errorf("expected HEAD response to keep the Content-Length of the body but got %+v", resp)
+	}
+}
+
+func TestNoContentResponsesHaveNoBody(t *testing.T) {
+	s := New(conditionalHandler)
+
+	resp, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/empty", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusNoContent || resp.Body != "" {
+		t.Errorf("expected 204 without a body but got %v %q", resp.StatusCode, resp.Body)
+	}
+}
+
+func TestResponsesWithoutWritesDefaultTo200(t *testing.T) {
+	s := New(conditionalHandler)
+
+	resp, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodOptions, "/options", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code to be %v but was %v", http.StatusOK, resp.StatusCode)
+	}
+	if resp.Headers["Allow"] != "GET, HEAD, OPTIONS" {
+		t.Errorf("expected Allow header to be kept but got %+v", resp.Headers)
+	}
+}
+
+func TestAutoETag(t *testing.T) {
+	s := New(conditionalHandler, WithAutoETag())
+
+	resp, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	etag := resp.Headers["Etag"]
+	if etag == "" || resp.StatusCode != http.StatusOK || resp.Body != "hello, world" {
+		t.Fatalf("expected 200 with an ETag but got %+v", resp)
+	}
+
+	resp, err = s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/", map[string]string{
+		"if-none-match": `"other", ` + etag,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusNotModified || resp.Body != "" {
+		t.Errorf("expected 304 without a body but got %v %q", resp.StatusCode, resp.Body)
+	}
+	if resp.Headers["Etag"] != etag {
+		t.Errorf("expected 304 to carry the ETag but got %+v", resp.Headers)
+	}
+	if _, ok := resp.Headers["Content-Type"]; ok {
+		t.Errorf("expected 304 to drop Content-Type but got %+v", resp.Headers)
+	}
+}
+
+func TestAutoETagRestApi(t *testing.T) {
+	s := New(conditionalHandler, WithAutoETag())
+
+	resp, err := s.Handle(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/tagged",
+		Headers:    map[string]string{"If-None-Match": `W/"v1"`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusNotModified || resp.Body != "" {
+		t.Errorf("expected handler ETag to be matched weakly but got %v %q", resp.StatusCode, resp.Body)
+	}
+}
+
+func TestAutoETagHeadWithServeContent(t *testing.T) {
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader("hello, world"))
+	}), WithAutoETag())
+
+	get, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/hello.txt", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if get.Headers["Etag"] == "" {
+		t.Fatalf("expected GET to get an ETag but got %+v", get.Headers)
+	}
+
+	head, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodHead, "/hello.txt", map[string]string{
+		"if-none-match": get.Headers["Etag"],
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag, ok := head.Headers["Etag"]; ok {
+		t.Errorf("expected no ETag from the hash of the empty HEAD body but got %v", etag)
+	}
+	if head.StatusCode != http.StatusOK || head.Headers["Content-Length"] != "12" {
+		t.Errorf("expected HEAD to keep the status and Content-Length of the GET response but got %v %+v", head.StatusCode, head.Headers)
+	}
+}
+
+func TestAutoETagIgnoresOtherMethods(t *testing.T) {
+	s := New(conditionalHandler, WithAutoETag())
+
+	resp, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodPost, "/", map[string]string{"if-none-match": "*"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK || resp.Headers["Etag"] != "" {
+		t.Errorf("expected POST to be left alone but got %+v", resp)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		ifNoneMatch string
+		etag        string
+		match       bool
+	}{
+		{`"a"`, `"a"`, true},
+		{`W/"a"`, `"a"`, true},
+		{`"a"`, `W/"a"`, true},
+		{`"b", "a"`, `"a"`, true},
+		{`"a,b"`, `"a"`, false},
+		{`"x", "a,b"`, `"a,b"`, true},
+		{`*`, `"a"`, true},
+		{``, `"a"`, false},
+		{`a`, `"a"`, false},
+	}
+
+	for _, test := range tests {
+		if got := etagMatches(test.ifNoneMatch, test.etag); got != test.match {
+			t.Errorf("etagMatches(%q, %q) = %v, expected %v", test.ifNoneMatch, test.etag, got, test.match)
+		}
+	}
+}
+
+func v2Request(method, path string, headers map[string]string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		RawPath: path,
+		Headers: headers,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: method},
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package shim
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// EventQuery holds the query parameters of a REST API or HTTP API payload format 1.0 event as API Gateway sent them.
+// These events have no raw query string, so verifiers that need the parameters exactly as they arrived, e.g. for HMAC
+// signatures, can use these instead of the rebuilt URL.RawQuery.
+type EventQuery struct {
+	QueryStringParameters           map[string]string
+	MultiValueQueryStringParameters map[string][]string
+}
+
+type eventQueryContextKey struct{}
+
+// EventQueryFromContext returns the query parameters of the REST style event that produced the request, if any
+func EventQueryFromContext(ctx context.Context) (EventQuery, bool) {
+	q, ok := ctx.Value(eventQueryContextKey{}).(EventQuery)
+	return q, ok
+}
+
+// rawQueryFromEvent rebuilds a raw query string from the query parameter maps of a REST style event. The order of the
+// keys is lost in the event, so they are sorted, but repeated values keep the order the client sent them in. Values
+// are percent-encoded per RFC 3986 with spaces as %20, which is also how SigV4 canonicalizes them, instead of the
+// form encoding url.Values.Encode uses.
+func rawQueryFromEvent(single map[string]string, multi map[string][]string) string {
+	if len(multi) == 0 && len(single) > 0 {
+		multi = make(map[string][]string, len(single))
+		for k, v := range single {
+			multi[k] = []string{v}
+		}
+	}
+
+	keys := make([]string, 0, len(multi))
+	for k := range multi {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range multi[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(sigv4Escape(k, true))
+			b.WriteByte('=')
+			b.WriteString(sigv4Escape(v, true))
+		}
+	}
+
+	return b.String()
+}
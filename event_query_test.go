@@ -0,0 +1,75 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestRawQueryFromEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		single   map[string]string
+		multi    map[string][]string
+		expected string
+	}{
+		{"empty", nil, nil, ""},
+		{"single", map[string]string{"b": "2", "a": "1"}, nil, "a=1&b=2"},
+		{
+			"repeated values keep their order",
+			map[string]string{"tag": "z"},
+			map[string][]string{"tag": {"z", "a", "m"}},
+			"tag=z&tag=a&tag=m",
+		},
+		{
+			"rfc 3986 encoding",
+			nil,
+			map[string][]string{"q": {"a b+c/d~e*f"}, "redirect_uri": {"https://example.com/cb?x=1"}},
+			"q=a%20b%2Bc%2Fd~e%2Af&redirect_uri=https%3A%2F%2Fexample.com%2Fcb%3Fx%3D1",
+		},
+		{"unicode", map[string]string{"name": "café"}, nil, "name=caf%C3%A9"},
+		{"empty value", map[string]string{"flag": ""}, nil, "flag="},
+	}
+
+	for _, test := range tests {
+		if got := rawQueryFromEvent(test.single, test.multi); got != test.expected {
+			t.Errorf("%v: expected %q but got %q", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestRestRequestQuery(t *testing.T) {
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod:                      http.MethodGet,
+		Path:                            "/webhook",
+		QueryStringParameters:           map[string]string{"id": "3", "ts": "1700000000"},
+		MultiValueQueryStringParameters: map[string][]string{"id": {"2", "1", "3"}, "ts": {"1700000000"}},
+	}
+
+	req, err := NewHttpRequestFromAPIGatewayProxyRequest(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := req.URL.Query()["id"]; !reflect.DeepEqual(ids, []string{"2", "1", "3"}) {
+		t.Errorf("expected repeated values in their original order but got %v", ids)
+	}
+
+	q, ok := EventQueryFromContext(req.Context())
+	if !ok {
+		t.Fatal("expected the event query in the context")
+	}
+	if !reflect.DeepEqual(q.MultiValueQueryStringParameters, event.MultiValueQueryStringParameters) ||
+		!reflect.DeepEqual(q.QueryStringParameters, event.QueryStringParameters) {
+		t.Errorf("expected the event query maps as they arrived but got %+v", q)
+	}
+}
+
+func TestEventQueryFromContextWithoutEvent(t *testing.T) {
+	if _, ok := EventQueryFromContext(context.Background()); ok {
+		t.Error("expected no event query")
+	}
+}
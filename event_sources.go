@@ -0,0 +1,93 @@
+package shim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// DefaultSQSPath is where SQS messages are sent. {queue} is replaced with the queue name.
+	DefaultSQSPath = "/_events/sqs/{queue}"
+	// DefaultSNSPath is where SNS notifications are sent. {topic} is replaced with the topic name.
+	DefaultSNSPath = "/_events/sns/{topic}"
+	// DefaultEventBridgePath is where EventBridge events are sent. {source} and {detail-type} are replaced with the
+	// event's source and detail type.
+	DefaultEventBridgePath = "/_events/eventbridge/{source}/{detail-type}"
+	// DefaultSchedulePath is where scheduled events are sent. {rule} is replaced with the name of the rule that fired.
+	DefaultSchedulePath = "/_events/schedule/{rule}"
+
+	// HeaderEventSource is set on synthetic event requests to the service that produced the record, e.g. aws:sqs
+	HeaderEventSource = "X-Shim-Event-Source"
+
+	scheduledEventDetailType = "Scheduled Event"
+)
+
+// EventPaths configures the paths non-HTTP Lambda triggers are sent to. Empty fields use the defaults.
+type EventPaths struct {
+	SQS         string
+	SNS         string
+	EventBridge string
+	Schedule    string
+}
+
+// WithEventPaths is an option function to change the paths SQS, SNS, EventBridge and scheduled events are sent to
+func WithEventPaths(p EventPaths) func(*Shim) {
+	return func(s *Shim) {
+		s.EventPaths = p
+	}
+}
+
+func (p EventPaths) withDefaults() EventPaths {
+	if p.SQS == "" {
+		p.SQS = DefaultSQSPath
+	}
+	if p.SNS == "" {
+		p.SNS = DefaultSNSPath
+	}
+	if p.EventBridge == "" {
+		p.EventBridge = DefaultEventBridgePath
+	}
+	if p.Schedule == "" {
+		p.Schedule = DefaultSchedulePath
+	}
+
+	return p
+}
+
+// expandEventPath replaces each {name} in pattern with the path escaped value from vars
+func expandEventPath(pattern string, vars map[string]string) string {
+	oldnew := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		oldnew = append(oldnew, "{"+k+"}", url.PathEscape(v))
+	}
+
+	return strings.NewReplacer(oldnew...).Replace(pattern)
+}
+
+// arnResourceName returns the part of an ARN after the last ":" or "/", e.g. the queue name of an SQS queue ARN
+func arnResourceName(arn string) string {
+	return arn[strings.LastIndexAny(arn, ":/")+1:]
+}
+
+// NewHttpRequestFromEventRecord creates a synthetic POST *http.Request to path with record marshaled as the JSON body
+func NewHttpRequestFromEventRecord(ctx context.Context, path, source string, record interface{}) (*http.Request, error) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("shim could not marshal %v record: %w", source, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("shim could not create http request from %v record: %w", source, err)
+	}
+
+	req.Header.Set(httpHeaderContentType, "application/json")
+	req.Header.Set(HeaderEventSource, source)
+
+	return req, nil
+}
@@ -0,0 +1,139 @@
+package shim
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestExpandEventPath(t *testing.T) {
+	out := expandEventPath(DefaultEventBridgePath, map[string]string{"source": "com.example", "detail-type": "Order Placed"})
+	if out != "/_events/eventbridge/com.example/Order%20Placed" {
+		t.Errorf("unexpected path %v", out)
+	}
+}
+
+func TestArnResourceName(t *testing.T) {
+	cases := []struct {
+		in  string
+		out string
+	}{
+		{in: "arn:aws:sqs:us-east-1:123456789012:orders", out: "orders"},
+		{in: "arn:aws:events:us-east-1:123456789012:rule/nightly-report", out: "nightly-report"},
+		{in: "orders", out: "orders"},
+	}
+
+	for _, c := range cases {
+		if out := arnResourceName(c.in); out != c.out {
+			t.Errorf("for %v expected %v but was %v", c.in, c.out, out)
+		}
+	}
+}
+
+func TestHandleSQSEventsReportsBatchItemFailures(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_events/sqs/orders", func(w http.ResponseWriter, r *http.Request) {
+		var msg events.SQSMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if msg.Body == "poison" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+	})
+
+	s := New(mux)
+	arn := "arn:aws:sqs:us-east-1:123456789012:orders"
+	resp, err := s.HandleSQSEvents(context.Background(), events.SQSEvent{
+		Records: []events.SQSMessage{
+			{MessageId: "1", Body: "ok", EventSourceARN: arn},
+			{MessageId: "2", Body: "poison", EventSourceARN: arn},
+			{MessageId: "3", Body: "ok", EventSourceARN: arn},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.BatchItemFailures) != 1 || resp.BatchItemFailures[0].ItemIdentifier != "2" {
+		t.Errorf("expected message 2 to be the only failure but was %+v", resp.BatchItemFailures)
+	}
+}
+
+func TestHandleSNSEvents(t *testing.T) {
+	var got string
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Method + " " + r.URL.Path + " " + r.Header.Get(HeaderEventSource)
+	}))
+
+	err := s.HandleSNSEvents(context.Background(), events.SNSEvent{
+		Records: []events.SNSEventRecord{{SNS: events.SNSEntity{TopicArn: "arn:aws:sns:us-east-1:123456789012:alerts"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != "POST /_events/sns/alerts aws:sns" {
+		t.Errorf("unexpected request %v", got)
+	}
+}
+
+func TestHandleSNSEventsReturnsErrorOnFailure(t *testing.T) {
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	err := s.HandleSNSEvents(context.Background(), events.SNSEvent{
+		Records: []events.SNSEventRecord{{SNS: events.SNSEntity{MessageID: "1"}}},
+	})
+	if err == nil {
+		t.Error("expected an error when the handler fails")
+	}
+}
+
+func TestHandleEventBridgeEvents(t *testing.T) {
+	var path string
+	var body []byte
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ = io.ReadAll(r.Body)
+	}), WithEventPaths(EventPaths{Schedule: "/cron/{rule}"}))
+
+	err := s.HandleEventBridgeEvents(context.Background(), events.EventBridgeEvent{
+		ID:         "1",
+		Source:     "com.example.orders",
+		DetailType: "Order Placed",
+		Detail:     json.RawMessage(`{"id":42}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if path != "/_events/eventbridge/com.example.orders/Order Placed" {
+		t.Errorf("unexpected path %v", path)
+	}
+
+	var event events.EventBridgeEvent
+	if err := json.Unmarshal(body, &event); err != nil || string(event.Detail) != `{"id":42}` {
+		t.Errorf("expected the event as the body but was %s", body)
+	}
+
+	err = s.HandleEventBridgeEvents(context.Background(), events.EventBridgeEvent{
+		Source:     "aws.events",
+		DetailType: "Scheduled Event",
+		Resources:  []string{"arn:aws:events:us-east-1:123456789012:rule/nightly-report"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if path != "/cron/nightly-report" {
+		t.Errorf("expected the configured schedule path but was %v", path)
+	}
+}
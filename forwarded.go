@@ -0,0 +1,110 @@
+package shim
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	httpHeaderHost            = "Host"
+	httpHeaderForwardedFor    = "X-Forwarded-For"
+	httpHeaderForwardedProto  = "X-Forwarded-Proto"
+	httpHeaderForwardedPort   = "X-Forwarded-Port"
+	schemeHTTPS               = "https"
+	defaultForwardedHTTPSPort = "443"
+	defaultForwardedHTTPPort  = "80"
+)
+
+// setRequestOrigin fills in what net/http.Server would know about the connection. API Gateway only accepts HTTPS, so
+// the scheme defaults to https unless X-Forwarded-Proto says otherwise. The host falls back to domainName when the
+// event has no Host header. Missing X-Forwarded-* headers are added so the request looks the same as one that went
+// through a load balancer. req.RemoteAddr must already be set.
+func setRequestOrigin(req *http.Request, domainName string) {
+	host := req.Header.Get(httpHeaderHost)
+	if host == "" {
+		host = domainName
+	}
+	req.Host = host
+	req.URL.Host = host
+
+	proto := req.Header.Get(httpHeaderForwardedProto)
+	if proto == "" {
+		proto = schemeHTTPS
+		req.Header.Set(httpHeaderForwardedProto, proto)
+	}
+	req.URL.Scheme = proto
+
+	if req.Header.Get(httpHeaderForwardedPort) == "" {
+		port := defaultForwardedHTTPPort
+		if proto == schemeHTTPS {
+			port = defaultForwardedHTTPSPort
+		}
+		req.Header.Set(httpHeaderForwardedPort, port)
+	}
+
+	if req.Header.Get(httpHeaderForwardedFor) == "" && req.RemoteAddr != "" {
+		req.Header.Set(httpHeaderForwardedFor, req.RemoteAddr)
+	}
+
+	if proto == schemeHTTPS {
+		// API Gateway doesn't report the negotiated version, TLS 1.2 is the lowest it accepts on the default endpoint
+		req.TLS = &tls.ConnectionState{
+			Version:           tls.VersionTLS12,
+			HandshakeComplete: true,
+			ServerName:        hostname(host),
+		}
+	}
+}
+
+// hostname strips the port from host, if any
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+
+	return host
+}
+
+// WithTrustedProxies is an option function that sets how many proxies in front of API Gateway, e.g. a CloudFront
+// distribution, are trusted to append to X-Forwarded-For. The request's RemoteAddr becomes the client address those
+// proxies saw. A negative count trusts the whole chain and uses its first entry, which any client can forge. By default
+// X-Forwarded-For is ignored and RemoteAddr is the source IP API Gateway saw.
+func WithTrustedProxies(n int) func(*Shim) {
+	return func(s *Shim) {
+		s.TrustedProxies = n
+	}
+}
+
+// trustForwardedFor sets req.RemoteAddr from X-Forwarded-For according to TrustedProxies
+func (s *Shim) trustForwardedFor(req *http.Request) {
+	if s.TrustedProxies == 0 {
+		return
+	}
+
+	var chain []string
+	for _, v := range req.Header.Values(httpHeaderForwardedFor) {
+		for _, addr := range strings.Split(v, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				chain = append(chain, addr)
+			}
+		}
+	}
+
+	// API Gateway usually appends the address it saw, add it when it didn't
+	if req.RemoteAddr != "" && (len(chain) == 0 || chain[len(chain)-1] != req.RemoteAddr) {
+		chain = append(chain, req.RemoteAddr)
+	}
+
+	if len(chain) == 0 {
+		return
+	}
+
+	i := len(chain) - 1 - s.TrustedProxies
+	if s.TrustedProxies < 0 || i < 0 {
+		i = 0
+	}
+
+	req.RemoteAddr = chain[i]
+}
@@ -0,0 +1,115 @@
+package shim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestRequestOriginFromV2Event(t *testing.T) {
+	event := events.APIGatewayV2HTTPRequest{
+		RawPath: "/callback",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			DomainName: "abc123.execute-api.us-east-1.amazonaws.com",
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:   http.MethodGet,
+				SourceIP: "203.0.113.7",
+			},
+		},
+	}
+
+	req, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Host != "abc123.execute-api.us-east-1.amazonaws.com" {
+		t.Errorf("expected host to fall back to the domain name but was %q", req.Host)
+	}
+	if req.URL.String() != "https://abc123.execute-api.us-east-1.amazonaws.com/callback" {
+		t.Errorf("expected an absolute https url but got %v", req.URL)
+	}
+	if req.TLS == nil || req.TLS.ServerName != "abc123.execute-api.us-east-1.amazonaws.com" {
+		t.Errorf("expected a TLS connection state but got %+v", req.TLS)
+	}
+
+	expected := map[string]string{
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Port":  "443",
+		"X-Forwarded-For":   "203.0.113.7",
+	}
+	for k, v := range expected {
+		if req.Header.Get(k) != v {
+			t.Errorf("expected %v to be %q but was %q", k, v, req.Header.Get(k))
+		}
+	}
+}
+
+func TestRequestOriginFromRestEvent(t *testing.T) {
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/",
+		Headers: map[string]string{
+			"Host":              "api.example.com",
+			"X-Forwarded-Proto": "http",
+			"X-Forwarded-Port":  "8080",
+		},
+	}
+
+	req, err := NewHttpRequestFromAPIGatewayProxyRequest(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.URL.Scheme != "http" || req.Host != "api.example.com" {
+		t.Errorf("expected the forwarded scheme and host header to be used but got %v", req.URL)
+	}
+	if req.TLS != nil {
+		t.Errorf("expected no TLS state for plain http but got %+v", req.TLS)
+	}
+	if req.Header.Get("X-Forwarded-Port") != "8080" {
+		t.Errorf("expected the forwarded port to be kept but was %q", req.Header.Get("X-Forwarded-Port"))
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.RemoteAddr)
+	})
+
+	tests := []struct {
+		trusted  int
+		expected string
+	}{
+		{0, "198.51.100.2"},
+		{1, "192.0.2.1"},
+		{2, "203.0.113.7"},
+		{5, "203.0.113.7"},
+		{-1, "203.0.113.7"},
+	}
+
+	for _, test := range tests {
+		s := New(h, WithTrustedProxies(test.trusted))
+
+		event := events.APIGatewayProxyRequest{
+			HTTPMethod: http.MethodGet,
+			Path:       "/",
+			Headers:    map[string]string{"X-Forwarded-For": "203.0.113.7, 192.0.2.1"},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				Identity: events.APIGatewayRequestIdentity{SourceIP: "198.51.100.2"},
+			},
+		}
+
+		resp, err := s.Handle(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Body != test.expected {
+			t.Errorf("expected RemoteAddr with %v trusted proxies to be %v but was %v", test.trusted, test.expected, resp.Body)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// NewHttpRequestFromLambdaFunctionURLRequest creates an *http.Request from a context.Context and an
+// events.LambdaFunctionURLRequest. Function URLs use the HTTP API payload format version 2.0, so the event is converted
+// the same way as an events.APIGatewayV2HTTPRequest.
+func NewHttpRequestFromLambdaFunctionURLRequest(ctx context.Context, event events.LambdaFunctionURLRequest) (*http.Request, error) {
+	return NewHttpRequestFromAPIGatewayV2HTTPRequest(ctx, apiGatewayV2HTTPRequestFromFunctionURL(event))
+}
+
+func apiGatewayV2HTTPRequestFromFunctionURL(event events.LambdaFunctionURLRequest) events.APIGatewayV2HTTPRequest {
+	rc := event.RequestContext
+
+	var authorizer *events.APIGatewayV2HTTPRequestContextAuthorizerDescription
+	if rc.Authorizer != nil && rc.Authorizer.IAM != nil {
+		authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+			IAM: &events.APIGatewayV2HTTPRequestContextAuthorizerIAMDescription{
+				AccessKey: rc.Authorizer.IAM.AccessKey,
+				AccountID: rc.Authorizer.IAM.AccountID,
+				CallerID:  rc.Authorizer.IAM.CallerID,
+				UserARN:   rc.Authorizer.IAM.UserARN,
+				UserID:    rc.Authorizer.IAM.UserID,
+			},
+		}
+	}
+
+	return events.APIGatewayV2HTTPRequest{
+		Version:               event.Version,
+		RouteKey:              "$default",
+		RawPath:               event.RawPath,
+		RawQueryString:        event.RawQueryString,
+		Cookies:               event.Cookies,
+		Headers:               event.Headers,
+		QueryStringParameters: event.QueryStringParameters,
+		Body:                  event.Body,
+		IsBase64Encoded:       event.IsBase64Encoded,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:     "$default",
+			AccountID:    rc.AccountID,
+			Stage:        "$default",
+			RequestID:    rc.RequestID,
+			Authorizer:   authorizer,
+			APIID:        rc.APIID,
+			DomainName:   rc.DomainName,
+			DomainPrefix: rc.DomainPrefix,
+			Time:         rc.Time,
+			TimeEpoch:    rc.TimeEpoch,
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    rc.HTTP.Method,
+				Path:      rc.HTTP.Path,
+				Protocol:  rc.HTTP.Protocol,
+				SourceIP:  rc.HTTP.SourceIP,
+				UserAgent: rc.HTTP.UserAgent,
+			},
+		},
+	}
+}
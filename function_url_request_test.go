@@ -0,0 +1,51 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestNewHttpRequestFromLambdaFunctionURLRequest(t *testing.T) {
+	event := events.LambdaFunctionURLRequest{
+		Version:        "2.0",
+		RawPath:        "/orders/42",
+		RawQueryString: "expand=items",
+		Headers: map[string]string{
+			"host":         "abc123.lambda-url.us-east-1.on.aws",
+			"content-type": "application/json",
+		},
+		Body: `{"ok":true}`,
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			RequestID: "request-id",
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method:   http.MethodPost,
+				Path:     "/orders/42",
+				SourceIP: "203.0.113.7",
+			},
+		},
+	}
+
+	req, err := NewHttpRequestFromLambdaFunctionURLRequest(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Method != http.MethodPost {
+		t.Errorf("expected method to be POST but was %v", req.Method)
+	}
+	if req.URL.Path != "/orders/42" || req.URL.Query().Get("expand") != "items" {
+		t.Errorf("unexpected url %v", req.URL)
+	}
+	if req.Host != "abc123.lambda-url.us-east-1.on.aws" {
+		t.Errorf("unexpected host %v", req.Host)
+	}
+	if req.RemoteAddr != "203.0.113.7" {
+		t.Errorf("unexpected remote addr %v", req.RemoteAddr)
+	}
+	if req.ContentLength != int64(len(event.Body)) {
+		t.Errorf("expected content length to be %v but was %v", len(event.Body), req.ContentLength)
+	}
+}
@@ -0,0 +1,117 @@
+package shim
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// streamingResponseWriter is an http.ResponseWriter that writes the body straight to a Lambda response stream. The
+// status code and headers are committed by the first call to WriteHeader, Write or Flush, after which header changes
+// are ignored like they are by net/http.
+type streamingResponseWriter struct {
+	header http.Header
+	pipe   *io.PipeWriter
+
+	once      sync.Once
+	committed chan struct{}
+	response  *events.LambdaFunctionURLStreamingResponse
+}
+
+func newStreamingResponseWriter() (*streamingResponseWriter, *io.PipeReader) {
+	pr, pw := io.Pipe()
+
+	return &streamingResponseWriter{
+		header:    make(http.Header),
+		pipe:      pw,
+		committed: make(chan struct{}),
+		response:  &events.LambdaFunctionURLStreamingResponse{Body: pr},
+	}, pr
+}
+
+// Header adheres to the http.ResponseWriter interface
+func (w *streamingResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader adheres to the http.ResponseWriter interface
+func (w *streamingResponseWriter) WriteHeader(code int) {
+	w.once.Do(func() {
+		headers := make(map[string]string, len(w.header))
+		for k, vs := range w.header {
+			if k == httpHeaderSetCookie {
+				w.response.Cookies = append(w.response.Cookies, vs...)
+				continue
+			}
+			headers[k] = strings.Join(vs, multipleValueSeperator)
+		}
+
+		w.response.StatusCode = code
+		w.response.Headers = headers
+		close(w.committed)
+	})
+}
+
+// Write adheres to the io.Writer interface. Writes block until the runtime has read them off the stream.
+func (w *streamingResponseWriter) Write(b []byte) (int, error) {
+	if w.header.Get(headerContentType) == "" {
+		w.header.Set(headerContentType, http.DetectContentType(b))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	return w.pipe.Write(b)
+}
+
+// Flush adheres to the http.Flusher interface. Writes go straight to the stream, so flushing only commits the headers.
+func (w *streamingResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// FlushError is called by http.ResponseController.Flush
+func (w *streamingResponseWriter) FlushError() error {
+	w.Flush()
+	return nil
+}
+
+// finish commits the headers if the handler never wrote anything and ends the stream
+func (w *streamingResponseWriter) finish(err error) {
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.pipe.CloseWithError(err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.pipe.Close()
+}
+
+// serveStreaming runs h in its own goroutine and returns the streaming response as soon as h commits its headers. The
+// body is streamed from h until it returns. A panic in h ends the stream with an error, or turns into a 500 if nothing
+// was written yet.
+func serveStreaming(h http.Handler, req *http.Request) (*events.LambdaFunctionURLStreamingResponse, error) {
+	w, pr := newStreamingResponseWriter()
+
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				w.finish(fmt.Errorf("shim recovered from panic in streaming handler: %v", v))
+				return
+			}
+			w.finish(nil)
+		}()
+
+		h.ServeHTTP(w, req)
+	}()
+
+	select {
+	case <-w.committed:
+		return w.response, nil
+	case <-req.Context().Done():
+		pr.CloseWithError(req.Context().Err())
+		return nil, req.Context().Err()
+	}
+}
@@ -0,0 +1,179 @@
+package shim
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// streamPrelude is the JSON document the runtime sends ahead of a streamed body
+type streamPrelude struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers"`
+	Cookies    []string          `json:"cookies"`
+}
+
+// readStreamPrelude reads a streaming response the way the Lambda runtime does and returns the prelude and a reader
+// positioned at the start of the body
+func readStreamPrelude(t *testing.T, r io.Reader) (streamPrelude, *bufio.Reader) {
+	t.Helper()
+
+	br := bufio.NewReader(r)
+	var raw []byte
+	for !bytes.HasSuffix(raw, make([]byte, 8)) {
+		b, err := br.ReadByte()
+		if err != nil {
+			t.Fatalf("could not read stream prelude: %v", err)
+		}
+		raw = append(raw, b)
+	}
+
+	var p streamPrelude
+	if err := json.Unmarshal(raw[:len(raw)-8], &p); err != nil {
+		t.Fatalf("could not decode stream prelude %q: %v", raw, err)
+	}
+
+	return p, br
+}
+
+func functionURLRequest(method, path string) events.LambdaFunctionURLRequest {
+	return events.LambdaFunctionURLRequest{
+		Version: "2.0",
+		RawPath: path,
+		Headers: map[string]string{"host": "abc123.lambda-url.us-east-1.on.aws"},
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			RequestID: "request-id",
+			HTTP:      events.LambdaFunctionURLRequestContextHTTPDescription{Method: method, Path: path},
+		},
+	}
+}
+
+func TestHandleFunctionURLStreamingRequestsStreamsEvents(t *testing.T) {
+	next := make(chan struct{})
+	done := make(chan struct{})
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+
+		sse := NewSSE(w)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		sse.Send(SSEEvent{ID: "1", Data: "first"})
+
+		// The first event has to reach the client before the handler goes on
+		<-next
+		sse.Send(SSEEvent{ID: "2", Event: "done", Data: "second\nline"})
+	})
+
+	s := New(h)
+	resp, err := s.HandleFunctionURLStreamingRequests(context.Background(), functionURLRequest(http.MethodGet, "/events"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Close()
+
+	prelude, body := readStreamPrelude(t, resp)
+	if prelude.StatusCode != http.StatusOK {
+		t.Errorf("expected status code to be %v but was %v", http.StatusOK, prelude.StatusCode)
+	}
+	if ct := prelude.Headers["Content-Type"]; ct != "text/event-stream" {
+		t.Errorf("expected Content-Type to be text/event-stream but was %q", ct)
+	}
+	if len(prelude.Cookies) != 1 || prelude.Cookies[0] != "session=abc" {
+		t.Errorf("expected cookies in the prelude but got %v", prelude.Cookies)
+	}
+
+	first := readEvent(t, body)
+	if first != "id: 1\ndata: first\n" {
+		t.Errorf("unexpected first event %q", first)
+	}
+
+	close(next)
+
+	second := readEvent(t, body)
+	if second != "id: 2\nevent: done\ndata: second\ndata: line\n" {
+		t.Errorf("unexpected second event %q", second)
+	}
+
+	if rest, _ := io.ReadAll(body); len(rest) != 0 {
+		t.Errorf("expected stream to end after the handler returned but got %q", rest)
+	}
+	<-done
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	var b strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("could not read event: %v", err)
+		}
+		if line == "\n" {
+			return b.String()
+		}
+		b.WriteString(line)
+	}
+}
+
+func TestHandleFunctionURLStreamingRequestsWithoutWrites(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	resp, err := New(h).HandleFunctionURLStreamingRequests(context.Background(), functionURLRequest(http.MethodDelete, "/thing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prelude, body := readStreamPrelude(t, resp)
+	if prelude.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status code to be %v but was %v", http.StatusNoContent, prelude.StatusCode)
+	}
+	if rest, _ := io.ReadAll(body); len(rest) != 0 {
+		t.Errorf("expected empty body but got %q", rest)
+	}
+}
+
+func TestHandleFunctionURLStreamingRequestsPanic(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	resp, err := New(h).HandleFunctionURLStreamingRequests(context.Background(), functionURLRequest(http.MethodGet, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prelude, body := readStreamPrelude(t, resp)
+	if prelude.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status code to be %v but was %v", http.StatusInternalServerError, prelude.StatusCode)
+	}
+	if _, err := io.ReadAll(body); err == nil {
+		t.Error("expected the stream to end with an error")
+	}
+}
+
+func TestHandleFunctionURLStreamingRequestsTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := New(h).HandleFunctionURLStreamingRequests(ctx, functionURLRequest(http.MethodGet, "/")); err == nil {
+		t.Error("expected an error when the handler never responds")
+	}
+}
@@ -0,0 +1,148 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// InvocationInfo describes the Lambda invocation that produced a request
+type InvocationInfo struct {
+	// FunctionARN is the ARN the function was invoked with, including the alias or version if one was used
+	FunctionARN     string
+	FunctionName    string
+	FunctionVersion string
+	MemoryLimitMB   int
+	// Deadline is when the invocation times out, zero if it is unknown
+	Deadline time.Time
+	// ColdStart is true for the first invocation the Shim handles in this execution environment
+	ColdStart bool
+}
+
+// RemainingTime returns the time left until the invocation times out, 0 if the deadline is unknown or has passed
+func (i InvocationInfo) RemainingTime() time.Duration {
+	if i.Deadline.IsZero() {
+		return 0
+	}
+
+	if remaining := time.Until(i.Deadline); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// Qualifier returns the alias or version the function was invoked with, "" if it was invoked with an unqualified ARN
+func (i InvocationInfo) Qualifier() string {
+	parts := strings.Split(i.FunctionARN, ":")
+	if len(parts) != 8 {
+		return ""
+	}
+
+	return parts[7]
+}
+
+// SyntheticInvocation holds the values InvocationInfo falls back to when there is no Lambda environment, e.g. when the
+// Shim is called from tests or a local server
+type SyntheticInvocation struct {
+	FunctionARN   string
+	MemoryLimitMB int
+	// Timeout sets the Deadline of invocations whose context has none
+	Timeout time.Duration
+}
+
+// DefaultSyntheticInvocation is used outside of Lambda unless WithSyntheticInvocation says otherwise
+var DefaultSyntheticInvocation = SyntheticInvocation{
+	FunctionARN:   "arn:aws:lambda:us-east-1:123456789012:function:shim",
+	MemoryLimitMB: 128,
+	Timeout:       3 * time.Second,
+}
+
+type invocationContextKey struct{}
+
+// WithSyntheticInvocation is an option function to set the invocation values handlers see when there is no Lambda
+// environment
+func WithSyntheticInvocation(si SyntheticInvocation) func(*Shim) {
+	return func(s *Shim) {
+		s.SyntheticInvocation = &si
+	}
+}
+
+// Invocation returns the Lambda invocation that produced r. Requests that didn't go through a Shim get the values of
+// the Lambda environment, or DefaultSyntheticInvocation outside of Lambda, and are never cold starts.
+func Invocation(r *http.Request) InvocationInfo {
+	if info, ok := r.Context().Value(invocationContextKey{}).(InvocationInfo); ok {
+		return info
+	}
+
+	return newInvocationInfo(r.Context(), DefaultSyntheticInvocation)
+}
+
+// startInvocation records the invocation in ctx. Entry points that delegate to other entry points keep the invocation
+// they already recorded, so cold starts are only counted once.
+func (s *Shim) startInvocation(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(invocationContextKey{}).(InvocationInfo); ok {
+		return ctx
+	}
+
+	synthetic := DefaultSyntheticInvocation
+	if s.SyntheticInvocation != nil {
+		synthetic = *s.SyntheticInvocation
+	}
+
+	info := newInvocationInfo(ctx, synthetic)
+	info.ColdStart = s.invocations.Add(1) == 1
+	ctx = context.WithValue(ctx, invocationContextKey{}, info)
+
+	if info.ColdStart {
+		for _, hook := range s.coldStartHooks {
+			hook(ctx)
+		}
+	}
+
+	return ctx
+}
+
+// OnColdStart registers a hook that runs at the start of the first invocation, before the event is converted and
+// passed to the handler. Hooks run in the order they were registered and must be registered before lambda.Start.
+func (s *Shim) OnColdStart(hook func(ctx context.Context)) {
+	s.coldStartHooks = append(s.coldStartHooks, hook)
+}
+
+func newInvocationInfo(ctx context.Context, synthetic SyntheticInvocation) InvocationInfo {
+	info := InvocationInfo{
+		FunctionARN:     synthetic.FunctionARN,
+		FunctionName:    lambdacontext.FunctionName,
+		FunctionVersion: lambdacontext.FunctionVersion,
+		MemoryLimitMB:   lambdacontext.MemoryLimitInMB,
+	}
+
+	if lc, ok := lambdacontext.FromContext(ctx); ok && lc.InvokedFunctionArn != "" {
+		info.FunctionARN = lc.InvokedFunctionArn
+	}
+
+	if info.FunctionName == "" {
+		if parts := strings.Split(info.FunctionARN, ":"); len(parts) >= 7 {
+			info.FunctionName = parts[6]
+		}
+	}
+
+	if info.FunctionVersion == "" {
+		info.FunctionVersion = "$LATEST"
+	}
+
+	if info.MemoryLimitMB == 0 {
+		info.MemoryLimitMB = synthetic.MemoryLimitMB
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		info.Deadline = deadline
+	} else if synthetic.Timeout > 0 {
+		info.Deadline = time.Now().Add(synthetic.Timeout)
+	}
+
+	return info
+}
@@ -0,0 +1,93 @@
+package shim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// invocationRecorder remembers the invocation of the last request it served
+type invocationRecorder struct {
+	info InvocationInfo
+}
+
+func (ir *invocationRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ir.info = Invocation(r)
+}
+
+func TestInvocationFromLambdaContext(t *testing.T) {
+	ir := &invocationRecorder{}
+	s := New(ir)
+
+	arn := "arn:aws:lambda:eu-west-1:123456789012:function:orders:live"
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{InvokedFunctionArn: arn})
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	if _, err := s.Handle(ctx, restRequest(http.MethodGet, "/")); err != nil {
+		t.Fatal(err)
+	}
+
+	if ir.info.FunctionARN != arn || ir.info.FunctionName != "orders" || ir.info.Qualifier() != "live" {
+		t.Errorf("expected the invoked function ARN but got %+v", ir.info)
+	}
+	if !ir.info.ColdStart {
+		t.Error("expected the first invocation to be a cold start")
+	}
+	if remaining := ir.info.RemainingTime(); remaining <= 50*time.Second || remaining > time.Minute {
+		t.Errorf("expected the remaining time of the context deadline but got %v", remaining)
+	}
+
+	if _, err := s.HandleHttpApiRequests(ctx, v2Request(http.MethodGet, "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if ir.info.ColdStart {
+		t.Error("expected later invocations not to be cold starts")
+	}
+}
+
+func TestInvocationSynthetic(t *testing.T) {
+	ir := &invocationRecorder{}
+	s := New(ir, WithSyntheticInvocation(SyntheticInvocation{
+		FunctionARN:   "arn:aws:lambda:us-west-2:000000000000:function:local",
+		MemoryLimitMB: 512,
+		Timeout:       10 * time.Second,
+	}))
+
+	if _, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if ir.info.FunctionName != "local" || ir.info.MemoryLimitMB != 512 || ir.info.Qualifier() != "" {
+		t.Errorf("expected the synthetic invocation but got %+v", ir.info)
+	}
+	if remaining := ir.info.RemainingTime(); remaining <= 9*time.Second || remaining > 10*time.Second {
+		t.Errorf("expected the synthetic timeout but got %v", remaining)
+	}
+}
+
+func TestInvocationCountsDelegatedEntryPointsOnce(t *testing.T) {
+	ir := &invocationRecorder{}
+	s := New(ir)
+
+	payload, _ := json.Marshal(v2Request(http.MethodGet, "/", nil))
+	if _, err := s.HandleHttpApiRequestsAnyVersion(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if !ir.info.ColdStart {
+		t.Error("expected the first invocation to be a cold start through HandleHttpApiRequestsAnyVersion")
+	}
+}
+
+func TestInvocationWithoutShim(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+
+	info := Invocation(req)
+	if info.FunctionARN != DefaultSyntheticInvocation.FunctionARN || info.ColdStart {
+		t.Errorf("expected the default synthetic invocation but got %+v", info)
+	}
+}
@@ -0,0 +1,172 @@
+package shim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultLazyBuildTimeout is how long an invocation waits for a LazyHandler to be built unless
+// WithLazyBuildTimeout says otherwise
+const DefaultLazyBuildTimeout = 5 * time.Second
+
+var errLazyBuildTimeout = errors.New("shim: timed out building the handler")
+
+// LazyHandler builds its http.Handler on the first request instead of in main, so a router whose dependencies fail to
+// start answers with Unavailable instead of crashing the Lambda during init. A failed build is retried on the next
+// request until one succeeds.
+type LazyHandler struct {
+	// Build returns the handler. The context carries the values of the request that triggered the build but is only
+	// canceled once Timeout passed.
+	Build func(ctx context.Context) (http.Handler, error)
+	// Timeout is how long a request waits for Build, DefaultLazyBuildTimeout if zero. A build that is still running
+	// when a request gives up is awaited by the next request instead of starting another one.
+	Timeout time.Duration
+	// Unavailable serves requests while there is no handler, a plain 503 Service Unavailable if nil
+	Unavailable http.Handler
+
+	mu      sync.Mutex
+	handler http.Handler
+	pending *lazyBuild
+	log     func(ctx context.Context, format string, v ...interface{})
+}
+
+type lazyBuild struct {
+	done    chan struct{}
+	handler http.Handler
+	err     error
+}
+
+// NewLazy returns an initialized Shim whose handler is built by build on the first invocation, see LazyHandler
+func NewLazy(build func(ctx context.Context) (http.Handler, error), options ...func(*Shim)) *Shim {
+	l := &LazyHandler{Build: build}
+	s := New(l, options...)
+	l.log = s.printf
+
+	return s
+}
+
+// WithLazyBuildTimeout is an option function to set how long an invocation waits for the handler of NewLazy to be built
+func WithLazyBuildTimeout(d time.Duration) func(*Shim) {
+	return func(s *Shim) {
+		if l, ok := s.Handler.(*LazyHandler); ok {
+			l.Timeout = d
+		}
+	}
+}
+
+// WithUnavailableHandler is an option function to set what NewLazy responds with until its handler was built
+func WithUnavailableHandler(h http.Handler) func(*Shim) {
+	return func(s *Shim) {
+		if l, ok := s.Handler.(*LazyHandler); ok {
+			l.Unavailable = h
+		}
+	}
+}
+
+func (l *LazyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, err := l.get(r.Context())
+	if err == nil {
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	l.printf(r.Context(), "handler is unavailable: %v\n", err)
+	if l.Unavailable != nil {
+		l.Unavailable.ServeHTTP(w, r)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// get returns the handler, starting or awaiting a build if there is none yet
+func (l *LazyHandler) get(ctx context.Context) (http.Handler, error) {
+	l.mu.Lock()
+	if l.handler != nil {
+		defer l.mu.Unlock()
+		return l.handler, nil
+	}
+
+	// A build that failed after the request waiting for it gave up is retried right away
+	b := l.pending
+	if b == nil || b.failed() {
+		b = l.start(ctx)
+		l.pending = b
+	}
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.timeout())
+	defer timer.Stop()
+
+	select {
+	case <-b.done:
+	case <-timer.C:
+		return nil, errLazyBuildTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.pending == b {
+		l.pending = nil
+		if b.err == nil {
+			l.printf(ctx, "built handler\n")
+			l.handler = b.handler
+		}
+	}
+
+	return b.handler, b.err
+}
+
+// start runs Build in the background so a build that ignores its context can't hold the invocation past the timeout
+func (l *LazyHandler) start(ctx context.Context) *lazyBuild {
+	b := &lazyBuild{done: make(chan struct{})}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.timeout())
+
+	l.printf(ctx, "building handler\n")
+	go func() {
+		defer close(b.done)
+		defer cancel()
+		defer func() {
+			if v := recover(); v != nil {
+				b.handler, b.err = nil, fmt.Errorf("shim: recovered from panic while building the handler: %v", v)
+			}
+		}()
+
+		b.handler, b.err = l.Build(ctx)
+		if b.err == nil && b.handler == nil {
+			b.err = errors.New("shim: building the handler returned neither a handler nor an error")
+		}
+	}()
+
+	return b
+}
+
+func (b *lazyBuild) failed() bool {
+	select {
+	case <-b.done:
+		return b.err != nil
+	default:
+		return false
+	}
+}
+
+func (l *LazyHandler) timeout() time.Duration {
+	if l.Timeout <= 0 {
+		return DefaultLazyBuildTimeout
+	}
+
+	return l.Timeout
+}
+
+func (l *LazyHandler) printf(ctx context.Context, format string, v ...interface{}) {
+	if l.log != nil {
+		l.log(ctx, format, v...)
+	}
+}
@@ -0,0 +1,112 @@
+package shim
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+}
+
+func TestNewLazyRetriesFailedBuilds(t *testing.T) {
+	builds := 0
+	s := NewLazy(func(ctx context.Context) (http.Handler, error) {
+		builds++
+		if builds == 1 {
+			return nil, errors.New("database is down")
+		}
+		return okHandler(), nil
+	})
+
+	resp, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected a 503 while the build fails but got %v", resp.StatusCode)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err = s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Body != "ok" {
+			t.Errorf("expected the built handler to respond but got %v %q", resp.StatusCode, resp.Body)
+		}
+	}
+
+	if builds != 2 {
+		t.Errorf("expected the handler to be built once after the failure but it was built %v times", builds)
+	}
+}
+
+func TestNewLazyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	s := NewLazy(func(ctx context.Context) (http.Handler, error) {
+		<-release
+		return okHandler(), nil
+	}, WithLazyBuildTimeout(10*time.Millisecond), WithUnavailableHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("warming up"))
+	})))
+
+	resp, err := s.Handle(context.Background(), restRequest(http.MethodGet, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Body != "warming up" || resp.Headers["Retry-After"] != "1" {
+		t.Errorf("expected the unavailable handler while the build is slow but got %v %q %v", resp.StatusCode, resp.Body, resp.Headers)
+	}
+
+	// The next invocation picks up the build that is still running instead of starting another one
+	close(release)
+	resp, err = s.Handle(context.Background(), restRequest(http.MethodGet, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the handler of the slow build but got %v", resp.StatusCode)
+	}
+}
+
+func TestNewLazyRecoversFromPanics(t *testing.T) {
+	s := NewLazy(func(ctx context.Context) (http.Handler, error) {
+		panic("boom")
+	})
+
+	resp, err := s.Handle(context.Background(), restRequest(http.MethodGet, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected a 503 after a panicking build but got %v", resp.StatusCode)
+	}
+}
+
+func TestOnColdStart(t *testing.T) {
+	var calls []string
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "request")
+	}))
+	s.OnColdStart(func(ctx context.Context) {
+		calls = append(calls, "cold start")
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(calls) != 3 || calls[0] != "cold start" || calls[1] != "request" || calls[2] != "request" {
+		t.Errorf("expected the hook to run once before the first request but got %v", calls)
+	}
+}
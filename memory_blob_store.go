@@ -0,0 +1,80 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Blob is an object held by a MemoryBlobStore
+type Blob struct {
+	Body     []byte
+	Metadata BlobMetadata
+}
+
+// MemoryBlobStore is an in-memory BlobStore meant to stand in for S3 in tests and local development. It is also an
+// http.Handler that serves stored objects, so presigned URLs can be followed when BaseURL points at it.
+type MemoryBlobStore struct {
+	// BaseURL is prepended to keys to build the URLs PresignGet returns
+	BaseURL string
+
+	mu    sync.Mutex
+	blobs map[string]Blob
+}
+
+// NewMemoryBlobStore returns an empty MemoryBlobStore that presigns URLs under baseURL
+func NewMemoryBlobStore(baseURL string) *MemoryBlobStore {
+	return &MemoryBlobStore{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		blobs:   make(map[string]Blob),
+	}
+}
+
+// Put stores a copy of body under key
+func (m *MemoryBlobStore) Put(ctx context.Context, key string, body []byte, meta BlobMetadata) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := make([]byte, len(body))
+	copy(b, body)
+	m.blobs[key] = Blob{Body: b, Metadata: meta}
+
+	return nil
+}
+
+// PresignGet returns the URL of key under BaseURL. The URL does not expire.
+func (m *MemoryBlobStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return m.BaseURL + "/" + strings.TrimPrefix(key, "/"), nil
+}
+
+// Get returns the blob stored under key
+func (m *MemoryBlobStore) Get(key string) (Blob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.blobs[key]
+	return b, ok
+}
+
+// ServeHTTP serves the blob whose key is the request path, with the stored metadata as headers
+func (m *MemoryBlobStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, ok := m.Get(strings.TrimPrefix(r.URL.Path, "/"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if b.Metadata.ContentType != "" {
+		w.Header().Set(httpHeaderContentType, b.Metadata.ContentType)
+	}
+	if b.Metadata.ContentDisposition != "" {
+		w.Header().Set(httpHeaderContentDisposition, b.Metadata.ContentDisposition)
+	}
+	if b.Metadata.ContentEncoding != "" {
+		w.Header().Set(httpHeaderContentEncoding, b.Metadata.ContentEncoding)
+	}
+
+	w.Write(b.Body)
+}
@@ -0,0 +1,122 @@
+package shim
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ClientCert is the client certificate of a mutual TLS request as API Gateway sends it in the event. REST APIs and
+// HTTP APIs use the same shape.
+type ClientCert = events.APIGatewayV2HTTPRequestContextAuthenticationClientCert
+
+type clientCertContextKey struct{}
+
+var errNoCertificateInPEM = errors.New("no certificate found in client certificate PEM")
+
+// ClientCertFromContext returns the client certificate of the mutual TLS request that produced the request, if any.
+// Its presence means API Gateway verified the certificate against the truststore of the domain. The parsed certificate
+// is available as req.TLS.PeerCertificates[0].
+func ClientCertFromContext(ctx context.Context) (ClientCert, bool) {
+	cc, ok := ctx.Value(clientCertContextKey{}).(ClientCert)
+	return cc, ok
+}
+
+// APIGatewayProxyRequestWithClientCert is an events.APIGatewayProxyRequest that also keeps the client certificate REST
+// APIs with mutual TLS send in requestContext.identity.clientCert, which events.APIGatewayRequestIdentity has no field
+// for. Use it with HandleRestApiRequestsWithClientCert.
+type APIGatewayProxyRequestWithClientCert struct {
+	events.APIGatewayProxyRequest
+	ClientCert *ClientCert `json:"-"`
+}
+
+// UnmarshalJSON decodes the event and its client certificate
+func (r *APIGatewayProxyRequestWithClientCert) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &r.APIGatewayProxyRequest); err != nil {
+		return err
+	}
+
+	var v struct {
+		RequestContext struct {
+			Identity struct {
+				ClientCert *ClientCert `json:"clientCert"`
+			} `json:"identity"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	r.ClientCert = v.RequestContext.Identity.ClientCert
+	return nil
+}
+
+// NewHttpRequestFromAPIGatewayProxyRequestWithClientCert creates an *http.Request like
+// NewHttpRequestFromAPIGatewayProxyRequest and adds the client certificate to req.TLS.PeerCertificates
+func NewHttpRequestFromAPIGatewayProxyRequestWithClientCert(ctx context.Context, event APIGatewayProxyRequestWithClientCert) (*http.Request, error) {
+	req, err := NewHttpRequestFromAPIGatewayProxyRequest(ctx, event.APIGatewayProxyRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if event.ClientCert == nil {
+		return req, nil
+	}
+
+	return setClientCert(req, *event.ClientCert)
+}
+
+// setClientCert parses the PEM of cc into req.TLS.PeerCertificates and makes cc available through
+// ClientCertFromContext. API Gateway only sends the leaf certificate, not the chain it verified, so req.TLS.VerifiedChains
+// is left nil. Requests without a client certificate are returned as is.
+func setClientCert(req *http.Request, cc ClientCert) (*http.Request, error) {
+	if cc.ClientCertPem == "" {
+		return req, nil
+	}
+
+	certs, err := parseCertificates([]byte(cc.ClientCertPem))
+	if err != nil {
+		return nil, fmt.Errorf("shim could not parse client certificate: %w", err)
+	}
+
+	if req.TLS == nil {
+		req.TLS = &tls.ConnectionState{HandshakeComplete: true}
+	}
+	req.TLS.PeerCertificates = certs
+
+	return req.WithContext(context.WithValue(req.Context(), clientCertContextKey{}, cc)), nil
+}
+
+// parseCertificates parses every CERTIFICATE block in data, leaf first
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errNoCertificateInPEM
+	}
+
+	return certs, nil
+}
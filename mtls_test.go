@@ -0,0 +1,159 @@
+package shim
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func testClientCertPEM(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(4242),
+		Subject:      pkix.Name{CommonName: "partner.example.com", Organization: []string{"Partner"}},
+		NotBefore:    time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2034, time.January, 1, 0, 0, 0, 0, time.UTC),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestClientCertFromV2Event(t *testing.T) {
+	certPEM := testClientCertPEM(t)
+
+	event := events.APIGatewayV2HTTPRequest{
+		RawPath: "/",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+			Authentication: events.APIGatewayV2HTTPRequestContextAuthentication{
+				ClientCert: ClientCert{
+					ClientCertPem: certPEM,
+					SubjectDN:     "CN=partner.example.com,O=Partner",
+					SerialNumber:  "4242",
+				},
+			},
+		},
+	}
+
+	req, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.TLS == nil || len(req.TLS.PeerCertificates) != 1 {
+		t.Fatalf("expected one peer certificate but got %+v", req.TLS)
+	}
+
+	cert := req.TLS.PeerCertificates[0]
+	if cert.SerialNumber.Int64() != 4242 || cert.Subject.CommonName != "partner.example.com" {
+		t.Errorf("unexpected certificate %v %v", cert.SerialNumber, cert.Subject)
+	}
+	if !cert.NotAfter.Equal(time.Date(2034, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected validity %v", cert.NotAfter)
+	}
+	if req.TLS.VerifiedChains != nil {
+		t.Errorf("expected no verified chains without the chain API Gateway verified but got %v", req.TLS.VerifiedChains)
+	}
+
+	cc, ok := ClientCertFromContext(req.Context())
+	if !ok || cc.SubjectDN != "CN=partner.example.com,O=Partner" {
+		t.Errorf("expected client cert in the context but got %+v", cc)
+	}
+}
+
+func TestClientCertFromV2EventWithoutMTLS(t *testing.T) {
+	req, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(context.Background(), v2Request(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.TLS == nil || req.TLS.PeerCertificates != nil {
+		t.Errorf("expected TLS state without peer certificates but got %+v", req.TLS)
+	}
+	if _, ok := ClientCertFromContext(req.Context()); ok {
+		t.Error("expected no client cert in the context")
+	}
+}
+
+func TestClientCertWithInvalidPEM(t *testing.T) {
+	event := v2Request(http.MethodGet, "/", nil)
+	event.RequestContext.Authentication.ClientCert.ClientCertPem = "not a certificate"
+
+	if _, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(context.Background(), event); err == nil {
+		t.Error("expected an error for an unparseable client certificate")
+	}
+}
+
+func TestHandleRestApiRequestsWithClientCert(t *testing.T) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"httpMethod": "GET",
+		"path":       "/whoami",
+		"requestContext": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"sourceIp": "203.0.113.7",
+				"clientCert": map[string]interface{}{
+					"clientCertPem": testClientCertPEM(t),
+					"subjectDN":     "CN=partner.example.com,O=Partner",
+					"serialNumber":  "4242",
+					"validity": map[string]string{
+						"notBefore": "Jan 1 00:00:00 2024 GMT",
+						"notAfter":  "Jan 1 00:00:00 2034 GMT",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var request APIGatewayProxyRequestWithClientCert
+	if err := json.Unmarshal(payload, &request); err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Path != "/whoami" || request.ClientCert == nil || request.ClientCert.Validity.NotAfter != "Jan 1 00:00:00 2034 GMT" {
+		t.Fatalf("expected event and client cert to be decoded but got %+v", request)
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+
+	resp, err := New(h).HandleRestApiRequestsWithClientCert(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK || resp.Body != "partner.example.com" {
+		t.Errorf("expected handler to see the client certificate but got %v %q", resp.StatusCode, resp.Body)
+	}
+}
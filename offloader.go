@@ -0,0 +1,110 @@
+package shim
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultOffloadThreshold is the body size in bytes above which responses are offloaded. It leaves room for base64
+	// inflation under the Lambda response limit.
+	DefaultOffloadThreshold = 4 * 1024 * 1024
+
+	// DefaultOffloadExpiry is how long presigned URLs to offloaded responses stay valid
+	DefaultOffloadExpiry = 15 * time.Minute
+
+	httpHeaderContentDisposition = "Content-Disposition"
+	httpHeaderLocation           = "Location"
+)
+
+// BlobMetadata holds the response headers that are kept on a stored object
+type BlobMetadata struct {
+	ContentType        string
+	ContentDisposition string
+	ContentEncoding    string
+}
+
+// BlobStore stores response bodies and hands out URLs clients can download them from
+type BlobStore interface {
+	Put(ctx context.Context, key string, body []byte, meta BlobMetadata) error
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// Offloader moves large response bodies to a BlobStore and answers the client with a 303 redirect to a presigned URL
+type Offloader struct {
+	Store BlobStore
+	// Threshold is the body size in bytes above which responses are offloaded
+	Threshold int
+	// Expires is how long the presigned URL stays valid
+	Expires time.Duration
+	// Key returns the object key for a response. Keys default to a random name under "shim/".
+	Key func(req *http.Request) string
+}
+
+// NewOffloader returns an Offloader for store using DefaultOffloadThreshold and DefaultOffloadExpiry
+func NewOffloader(store BlobStore) *Offloader {
+	return &Offloader{
+		Store:     store,
+		Threshold: DefaultOffloadThreshold,
+		Expires:   DefaultOffloadExpiry,
+	}
+}
+
+// WithOffloader is an option function to offload large responses to a BlobStore, see Offloader
+func WithOffloader(o *Offloader) func(*Shim) {
+	return func(s *Shim) {
+		s.Offloader = o
+	}
+}
+
+// offload stores the body of rw and replaces the response with a redirect if the body is over the threshold. Only
+// successful responses are offloaded and HEAD responses never are, their body is dropped anyway. Compressed bodies are
+// stored compressed along with their Content-Encoding. Headers that don't describe the body, e.g. Set-Cookie, are kept
+// on the redirect.
+func (o *Offloader) offload(req *http.Request, rw *ResponseWriter) (bool, error) {
+	if rw.Body.Len() <= o.Threshold || (rw.Code != 0 && rw.Code != http.StatusOK) || req.Method == http.MethodHead {
+		return false, nil
+	}
+
+	key := o.key(req)
+	meta := BlobMetadata{
+		ContentType:        rw.Headers.Get(httpHeaderContentType),
+		ContentDisposition: rw.Headers.Get(httpHeaderContentDisposition),
+		ContentEncoding:    rw.Headers.Get(httpHeaderContentEncoding),
+	}
+
+	if err := o.Store.Put(req.Context(), key, rw.Body.Bytes(), meta); err != nil {
+		return false, fmt.Errorf("shim could not offload response body: %w", err)
+	}
+
+	expires := o.Expires
+	if expires == 0 {
+		expires = DefaultOffloadExpiry
+	}
+
+	location, err := o.Store.PresignGet(req.Context(), key, expires)
+	if err != nil {
+		return false, fmt.Errorf("shim could not presign offloaded response: %w", err)
+	}
+
+	rw.discardEntity()
+	rw.Header().Set(httpHeaderLocation, location)
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(http.StatusSeeOther)
+
+	return true, nil
+}
+
+func (o *Offloader) key(req *http.Request) string {
+	if o.Key != nil {
+		return o.Key(req)
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "shim/" + hex.EncodeToString(b)
+}
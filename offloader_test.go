@@ -0,0 +1,208 @@
+package shim
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHandleOffloadsLargeResponses(t *testing.T) {
+	store := NewMemoryBlobStore("https://blobs.example.com")
+	offloader := NewOffloader(store)
+	offloader.Threshold = 100
+	offloader.Key = func(req *http.Request) string { return "reports/1.csv" }
+
+	report := strings.Repeat("a,b,c\n", 100)
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(httpHeaderContentType, "text/csv")
+		w.Header().Set(httpHeaderContentDisposition, `attachment; filename="report.csv"`)
+		w.Write([]byte(report))
+	}), WithOffloader(offloader))
+
+	resp, err := s.Handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/report"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected status code 303 but was %v", resp.StatusCode)
+	}
+
+	if location := resp.Headers[httpHeaderLocation]; location != "https://blobs.example.com/reports/1.csv" {
+		t.Errorf("unexpected location %v", location)
+	}
+
+	blob, ok := store.Get("reports/1.csv")
+	if !ok {
+		t.Fatal("expected body to be stored")
+	}
+
+	if string(blob.Body) != report {
+		t.Error("expected stored body to match the handler's body")
+	}
+
+	if blob.Metadata.ContentType != "text/csv" || blob.Metadata.ContentDisposition != `attachment; filename="report.csv"` {
+		t.Errorf("expected content headers to be kept on the object but was %+v", blob.Metadata)
+	}
+}
+
+func TestHandleDoesNotOffloadSmallOrFailedResponses(t *testing.T) {
+	store := NewMemoryBlobStore("https://blobs.example.com")
+	offloader := NewOffloader(store)
+	offloader.Threshold = 10
+
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(strings.Repeat("x", 100)))
+			return
+		}
+		w.Write([]byte("small"))
+	}), WithOffloader(offloader))
+
+	for _, path := range []string{"/small", "/error"} {
+		resp, _ := s.Handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: path})
+		if resp.StatusCode == http.StatusSeeOther {
+			t.Errorf("expected %v not to be offloaded", path)
+		}
+	}
+}
+
+func TestHandleKeepsNonEntityHeadersOnOffloadedResponses(t *testing.T) {
+	offloader := NewOffloader(NewMemoryBlobStore("https://blobs.example.com"))
+	offloader.Threshold = 10
+
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+		w.Header().Set("Access-Control-Allow-Origin", "https://app.example.com")
+		w.Header().Set(httpHeaderContentType, "text/csv")
+		w.Header().Set(httpHeaderETag, `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 19 Oct 2026 00:00:00 GMT")
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}), WithOffloader(offloader))
+
+	resp, err := s.Handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/report"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected status code 303 but was %v", resp.StatusCode)
+	}
+
+	if cookies := resp.MultiValueHeaders[httpHeaderSetCookie]; len(cookies) != 2 {
+		t.Errorf("expected both cookies to be kept but got %v", cookies)
+	}
+
+	if origin := resp.Headers["Access-Control-Allow-Origin"]; origin != "https://app.example.com" {
+		t.Errorf("expected the CORS header to be kept but got %q", origin)
+	}
+
+	if resp.Headers[httpHeaderContentType] == "text/csv" {
+		t.Error("expected the Content-Type of the offloaded body to be dropped")
+	}
+
+	for _, h := range []string{httpHeaderETag, "Last-Modified"} {
+		if v, ok := resp.Headers[h]; ok {
+			t.Errorf("expected %v of the offloaded body to be dropped but got %q", h, v)
+		}
+	}
+}
+
+type failingBlobStore struct{}
+
+func (failingBlobStore) Put(ctx context.Context, key string, body []byte, meta BlobMetadata) error {
+	return errors.New("boom")
+}
+
+func (failingBlobStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return "", nil
+}
+
+func TestHandleSendsResponseInlineWhenOffloadFails(t *testing.T) {
+	offloader := NewOffloader(failingBlobStore{})
+	offloader.Threshold = 1
+
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello, world"))
+	}), WithOffloader(offloader))
+
+	resp, _ := s.Handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+	if resp.StatusCode != http.StatusOK || resp.Body != "hello, world" {
+		t.Errorf("expected the original response but was %+v", resp)
+	}
+}
+
+func TestMemoryBlobStoreServesBlobs(t *testing.T) {
+	store := NewMemoryBlobStore("")
+	store.Put(context.Background(), "a/b.txt", []byte("hello"), BlobMetadata{ContentType: "text/plain"})
+
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a/b.txt", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "hello" || rec.Header().Get(httpHeaderContentType) != "text/plain" {
+		t.Errorf("unexpected response %v %v %v", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
+
+func TestHandleCompressesBeforeOffloading(t *testing.T) {
+	store := NewMemoryBlobStore("https://blobs.example.com")
+	offloader := NewOffloader(store)
+	offloader.Threshold = 1000
+	offloader.Key = func(req *http.Request) string { return "reports/" + strings.TrimPrefix(req.URL.Path, "/") }
+
+	// A fixed seed keeps /random from being one of the hex bodies gzip can't shrink, which would be offloaded without a
+	// Content-Encoding. Compressed it is about 5KB, still well over the threshold.
+	random := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(httpHeaderContentType, "application/json")
+		if r.URL.Path == "/compressible" {
+			w.Write([]byte(strings.Repeat(`{"id":1,"name":"widget"},`, 400)))
+			return
+		}
+		w.Write([]byte(hex.EncodeToString(random)))
+	}), WithOffloader(offloader), WithCompression(100))
+
+	request := func(path string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{
+			HTTPMethod: http.MethodGet,
+			Path:       path,
+			Headers:    map[string]string{"Accept-Encoding": "gzip"},
+		}
+	}
+
+	resp, err := s.Handle(context.Background(), request("/compressible"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Headers[httpHeaderContentEncoding] != "gzip" {
+		t.Errorf("expected a body that compresses below the threshold to be returned compressed but got %v %v", resp.StatusCode, resp.Headers)
+	}
+
+	resp, err = s.Handle(context.Background(), request("/random"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected a body that is still too large compressed to be offloaded but got %v", resp.StatusCode)
+	}
+
+	blob, ok := store.Get("reports/random")
+	if !ok {
+		t.Fatal("expected body to be stored")
+	}
+	if blob.Metadata.ContentEncoding != "gzip" || len(blob.Body) < 2 || blob.Body[0] != 0x1f || blob.Body[1] != 0x8b {
+		t.Errorf("expected the compressed body to be stored with its encoding but got %+v", blob.Metadata)
+	}
+}
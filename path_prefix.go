@@ -0,0 +1,122 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PathPrefixStrategy returns the prefix to strip from a request path, or "" to leave the path alone. stage is the API
+// Gateway stage of the event, "$default" for the default stage of HTTP APIs and Function URLs.
+type PathPrefixStrategy func(path, stage string) string
+
+type pathPrefixContextKey struct{}
+
+// StripStage strips the stage from paths that start with it. HTTP APIs include named stages in the path on the
+// execute-api endpoint but not behind a custom domain.
+func StripStage() PathPrefixStrategy {
+	return func(path, stage string) string {
+		if stage == "" || stage == "$default" {
+			return ""
+		}
+
+		return matchPathPrefix(path, "/"+stage)
+	}
+}
+
+// StripBasePaths strips the first of basePaths the path starts with. REST APIs include the base path mapping of a
+// custom domain in the path.
+func StripBasePaths(basePaths ...string) PathPrefixStrategy {
+	return func(path, stage string) string {
+		for _, basePath := range basePaths {
+			if prefix := matchPathPrefix(path, "/"+strings.Trim(basePath, "/")); prefix != "" {
+				return prefix
+			}
+		}
+
+		return ""
+	}
+}
+
+// matchPathPrefix returns prefix if it is a whole segment prefix of path
+func matchPathPrefix(path, prefix string) string {
+	if prefix == "/" || !strings.HasPrefix(path, prefix) {
+		return ""
+	}
+
+	if len(path) > len(prefix) && path[len(prefix)] != '/' {
+		return ""
+	}
+
+	return prefix
+}
+
+// WithPathPrefixStrategy is an option function that strips a prefix from request paths before ServeHTTP, so the same
+// router serves the execute-api endpoint and custom domains. Strategies are tried in order and the first prefix found
+// is stripped:
+//
+//	shim.WithPathPrefixStrategy(shim.StripStage(), shim.StripBasePaths("/v1"))
+//
+// Use ExternalURL to build public URLs that include the stripped prefix.
+func WithPathPrefixStrategy(strategies ...PathPrefixStrategy) func(*Shim) {
+	return func(s *Shim) {
+		s.PathPrefixStrategies = strategies
+	}
+}
+
+// stripPathPrefix strips the prefix the first matching strategy returns and remembers it for ExternalURL
+func (s *Shim) stripPathPrefix(req *http.Request, stage string) *http.Request {
+	for _, strategy := range s.PathPrefixStrategies {
+		prefix := strategy(req.URL.Path, stage)
+		if prefix == "" {
+			continue
+		}
+
+		req.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, prefix), "/")
+		if req.URL.RawPath != "" {
+			req.URL.RawPath = "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.RawPath, prefix), "/")
+		}
+
+		s.printf(req.Context(), "stripped path prefix %v\n", prefix)
+		return req.WithContext(contextWithPathPrefix(req.Context(), PathPrefixFromContext(req.Context())+prefix))
+	}
+
+	return req
+}
+
+// contextWithPathPrefix remembers the part of the public path in front of the request path for ExternalURL
+func contextWithPathPrefix(ctx context.Context, prefix string) context.Context {
+	if prefix == "" || prefix == "/" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, pathPrefixContextKey{}, strings.TrimSuffix(prefix, "/"))
+}
+
+// PathPrefixFromContext returns the prefix in front of the request path in the public URL, if any. That is the stage
+// or base path REST events leave out of their path plus the prefix WithPathPrefixStrategy stripped.
+func PathPrefixFromContext(ctx context.Context) string {
+	prefix, _ := ctx.Value(pathPrefixContextKey{}).(string)
+	return prefix
+}
+
+// ExternalURL returns the public URL of path, an absolute path with an optional query, on the API that received r. The
+// prefix stripped by WithPathPrefixStrategy is added back, so the URL can be used for redirects and Location headers.
+func ExternalURL(r *http.Request, path string) string {
+	u := url.URL{Scheme: r.URL.Scheme, Host: r.Host}
+	if u.Scheme == "" {
+		u.Scheme = schemeHTTPS
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		ref = &url.URL{Path: path}
+	}
+
+	u.Path = PathPrefixFromContext(r.Context()) + "/" + strings.TrimPrefix(ref.Path, "/")
+	u.RawQuery = ref.RawQuery
+	u.Fragment = ref.Fragment
+
+	return u.String()
+}
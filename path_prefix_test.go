@@ -0,0 +1,117 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var prefixHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/orders" {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.Redirect(w, r, ExternalURL(r, "/orders/42?expand=items"), http.StatusSeeOther)
+})
+
+func TestStripStage(t *testing.T) {
+	s := New(prefixHandler, WithPathPrefixStrategy(StripStage()))
+
+	event := v2Request(http.MethodPost, "/prod/orders", map[string]string{"host": "abc123.execute-api.us-east-1.amazonaws.com"})
+	event.RequestContext.Stage = "prod"
+
+	resp, err := s.HandleHttpApiRequests(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected the stage to be stripped but got %v", resp.StatusCode)
+	}
+	if location := resp.Headers["Location"]; location != "https://abc123.execute-api.us-east-1.amazonaws.com/prod/orders/42?expand=items" {
+		t.Errorf("expected Location to include the stage but was %v", location)
+	}
+}
+
+func TestExternalURLRestStage(t *testing.T) {
+	s := New(prefixHandler)
+
+	resp, err := s.Handle(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/orders",
+		Headers:    map[string]string{"Host": "abc123.execute-api.us-east-1.amazonaws.com"},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Stage: "prod",
+			Path:  "/prod/orders",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected the REST path to be served as is but got %v", resp.StatusCode)
+	}
+	if location := resp.Headers["Location"]; location != "https://abc123.execute-api.us-east-1.amazonaws.com/prod/orders/42?expand=items" {
+		t.Errorf("expected Location to include the stage of the request context path but was %v", location)
+	}
+}
+
+func TestStripBasePaths(t *testing.T) {
+	s := New(prefixHandler, WithPathPrefixStrategy(StripStage(), StripBasePaths("/v1", "v2/")))
+
+	for _, path := range []string{"/v1/orders", "/v2/orders", "/orders"} {
+		event := events.APIGatewayProxyRequest{
+			HTTPMethod: http.MethodPost,
+			Path:       path,
+			Headers:    map[string]string{"Host": "api.example.com"},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				Stage: "prod",
+			},
+		}
+
+		resp, err := s.Handle(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Errorf("expected %v to be routed to /orders but got %v", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestStripBasePathsMatchesWholeSegments(t *testing.T) {
+	strategy := StripBasePaths("/v1")
+
+	tests := map[string]string{
+		"/v1":        "/v1",
+		"/v1/orders": "/v1",
+		"/v10":       "",
+		"/v1orders":  "",
+		"/orders/v1": "",
+	}
+
+	for path, expected := range tests {
+		if prefix := strategy(path, "$default"); prefix != expected {
+			t.Errorf("expected prefix of %v to be %q but was %q", path, expected, prefix)
+		}
+	}
+}
+
+func TestStripStageKeepsDefaultStage(t *testing.T) {
+	if prefix := StripStage()("/$default/orders", "$default"); prefix != "" {
+		t.Errorf("expected $default stage to be left alone but got %q", prefix)
+	}
+}
+
+func TestExternalURLWithoutPrefix(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "https://api.example.com/orders", nil)
+
+	if u := ExternalURL(r, "orders/1"); u != "https://api.example.com/orders/1" {
+		t.Errorf("unexpected external url %v", u)
+	}
+}
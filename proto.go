@@ -0,0 +1,51 @@
+package shim
+
+import (
+	"net/http"
+	"strings"
+)
+
+// connectionSpecificHeaders are not allowed in HTTP/2 and HTTP/3 responses, see RFC 9113 section 8.2.2
+var connectionSpecificHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// setProto sets the protocol version of req from the protocol in the event's request context, e.g. "HTTP/1.1" or
+// "HTTP/2.0". The HTTP/1.1 net/http.NewRequest defaults to is kept when the event has none or it can't be parsed.
+func setProto(req *http.Request, protocol string) {
+	if protocol == "" {
+		return
+	}
+
+	// Some clients report HTTP/2 and HTTP/3 without a minor version
+	if !strings.Contains(protocol, ".") {
+		protocol += ".0"
+	}
+
+	major, minor, ok := http.ParseHTTPVersion(strings.ToUpper(protocol))
+	if !ok {
+		return
+	}
+
+	req.Proto = strings.ToUpper(protocol)
+	req.ProtoMajor = major
+	req.ProtoMinor = minor
+}
+
+// dropConnectionHeaders removes headers from the response that are invalid for the protocol version of req
+func (s *Shim) dropConnectionHeaders(req *http.Request, rw *ResponseWriter) {
+	if req.ProtoMajor < 2 {
+		return
+	}
+
+	for _, h := range connectionSpecificHeaders {
+		if _, ok := rw.Headers[h]; ok {
+			s.printf(req.Context(), "dropping %v response header which is not allowed in %v\n", h, req.Proto)
+			rw.Headers.Del(h)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestSetProto(t *testing.T) {
+	tests := []struct {
+		protocol string
+		proto    string
+		major    int
+		minor    int
+	}{
+		{"", "HTTP/1.1", 1, 1},
+		{"HTTP/1.0", "HTTP/1.0", 1, 0},
+		{"HTTP/2.0", "HTTP/2.0", 2, 0},
+		{"HTTP/2", "HTTP/2.0", 2, 0},
+		{"http/3", "HTTP/3.0", 3, 0},
+		{"SPDY", "HTTP/1.1", 1, 1},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		setProto(req, test.protocol)
+
+		if req.Proto != test.proto || req.ProtoMajor != test.major || req.ProtoMinor != test.minor {
+			t.Errorf("expected %q to give %v (%v.%v) but got %v (%v.%v)",
+				test.protocol, test.proto, test.major, test.minor, req.Proto, req.ProtoMajor, req.ProtoMinor)
+		}
+	}
+}
+
+func TestProtoFromEvents(t *testing.T) {
+	v2 := v2Request(http.MethodGet, "/", nil)
+	v2.RequestContext.HTTP.Protocol = "HTTP/2.0"
+
+	req, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(context.Background(), v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !req.ProtoAtLeast(2, 0) {
+		t.Errorf("expected HTTP/2.0 from the v2 event but got %v", req.Proto)
+	}
+
+	rest := events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           "/",
+		RequestContext: events.APIGatewayProxyRequestContext{Protocol: "HTTP/1.0"},
+	}
+
+	req, err = NewHttpRequestFromAPIGatewayProxyRequest(context.Background(), rest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Proto != "HTTP/1.0" {
+		t.Errorf("expected HTTP/1.0 from the REST event but got %v", req.Proto)
+	}
+}
+
+func TestConnectionHeadersDroppedForHTTP2(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.Proto))
+	})
+
+	for _, protocol := range []string{"HTTP/1.1", "HTTP/2.0"} {
+		event := v2Request(http.MethodGet, "/", nil)
+		event.RequestContext.HTTP.Protocol = protocol
+
+		resp, err := New(h).HandleHttpApiRequests(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Body != protocol {
+			t.Errorf("expected handler to see %v but saw %v", protocol, resp.Body)
+		}
+
+		_, hasConnection := resp.Headers["Connection"]
+		_, hasKeepAlive := resp.Headers["Keep-Alive"]
+		if expected := protocol == "HTTP/1.1"; hasConnection != expected || hasKeepAlive != expected {
+			t.Errorf("expected connection headers present to be %v for %v but got %+v", expected, protocol, resp.Headers)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package shim
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var errInvalidBase64 = errors.New("illegal base64 data in request body")
+
+// setRequestBody points req.Body at the event body without copying it. Base64 encoded bodies are decoded as they are
+// read, ContentLength is the decoded length and GetBody returns a fresh reader so the body can be replayed.
+func setRequestBody(req *http.Request, body string, isBase64Encoded bool) error {
+	if body == "" {
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		req.ContentLength = 0
+		return nil
+	}
+
+	if !isBase64Encoded {
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(body)), nil
+		}
+		req.ContentLength = int64(len(body))
+		return nil
+	}
+
+	n, err := base64DecodedLen(body)
+	if err != nil {
+		return err
+	}
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(base64.NewDecoder(base64.StdEncoding, strings.NewReader(body))), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = int64(n)
+
+	return nil
+}
+
+// base64DecodedLen validates s as padded standard base64 and returns the exact length of the decoded data without
+// decoding it
+func base64DecodedLen(s string) (int, error) {
+	if len(s)%4 != 0 {
+		return 0, errInvalidBase64
+	}
+
+	padding := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '=':
+			// Padding is only allowed in the last two positions
+			if i < len(s)-2 {
+				return 0, errInvalidBase64
+			}
+			padding++
+		case padding > 0:
+			return 0, errInvalidBase64
+		case ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '+' || c == '/':
+		default:
+			return 0, errInvalidBase64
+		}
+	}
+
+	return len(s)/4*3 - padding, nil
+}
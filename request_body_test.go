@@ -0,0 +1,132 @@
+package shim
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestBase64DecodedLen(t *testing.T) {
+	for _, in := range []string{"", "a", "ab", "abc", "hello, world", string([]byte{0, 1, 2, 255})} {
+		encoded := base64.StdEncoding.EncodeToString([]byte(in))
+		n, err := base64DecodedLen(encoded)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", encoded, err)
+		}
+
+		if n != len(in) {
+			t.Errorf("for %q expected %v but was %v", encoded, len(in), n)
+		}
+	}
+
+	for _, in := range []string{"abc", "ab=c", "a===", "ab$=", "YQ==YQ=="} {
+		if _, err := base64DecodedLen(in); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestSetRequestBodyCanBeReplayed(t *testing.T) {
+	body := strings.Repeat("hello, world ", 100)
+	event := events.APIGatewayProxyRequest{
+		Path:            "/upload",
+		HTTPMethod:      http.MethodPost,
+		Body:            base64.StdEncoding.EncodeToString([]byte(body)),
+		IsBase64Encoded: true,
+	}
+
+	req, err := NewHttpRequestFromAPIGatewayProxyRequest(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.ContentLength != int64(len(body)) {
+		t.Errorf("expected ContentLength to be %v but was %v", len(body), req.ContentLength)
+	}
+
+	first, _ := io.ReadAll(req.Body)
+	replay, err := req.GetBody()
+	if err != nil {
+		t.Fatalf("unexpected error from GetBody: %v", err)
+	}
+	second, _ := io.ReadAll(replay)
+
+	if string(first) != body || string(second) != body {
+		t.Error("expected body to be readable twice")
+	}
+}
+
+func TestNewHttpRequestRejectsInvalidBase64(t *testing.T) {
+	event := events.APIGatewayV2HTTPRequest{
+		RawPath:         "/",
+		Body:            "not base64!",
+		IsBase64Encoded: true,
+	}
+
+	if _, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(context.Background(), event); err == nil {
+		t.Error("expected error for invalid base64 body")
+	}
+}
+
+func BenchmarkNewHttpRequestFromAPIGatewayProxyRequestBase64(b *testing.B) {
+	for _, size := range []int{1 << 10, 1 << 20} {
+		event := events.APIGatewayProxyRequest{
+			Path:            "/upload",
+			HTTPMethod:      http.MethodPost,
+			Body:            base64.StdEncoding.EncodeToString(make([]byte, size)),
+			IsBase64Encoded: true,
+		}
+
+		b.Run(byteSize(size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				req, err := NewHttpRequestFromAPIGatewayProxyRequest(context.Background(), event)
+				if err != nil {
+					b.Fatal(err)
+				}
+				io.Copy(io.Discard, req.Body)
+			}
+		})
+	}
+}
+
+func BenchmarkNewHttpRequestFromAPIGatewayV2HTTPRequestBase64(b *testing.B) {
+	for _, size := range []int{1 << 10, 1 << 20} {
+		event := events.APIGatewayV2HTTPRequest{
+			RawPath:         "/upload",
+			Body:            base64.StdEncoding.EncodeToString(make([]byte, size)),
+			IsBase64Encoded: true,
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodPost},
+			},
+		}
+
+		b.Run(byteSize(size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				req, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(context.Background(), event)
+				if err != nil {
+					b.Fatal(err)
+				}
+				io.Copy(io.Discard, req.Body)
+			}
+		})
+	}
+}
+
+func byteSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return strconv.Itoa(n>>20) + "MB"
+	case n >= 1<<10:
+		return strconv.Itoa(n>>10) + "KB"
+	default:
+		return strconv.Itoa(n) + "B"
+	}
+}
@@ -0,0 +1,166 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// DefaultRequestIDHeader is the header the request ID is set on unless a RequestIDPolicy says otherwise
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// RequestIDPolicy decides which request ID the handler sees and whether it is sent back to the client
+type RequestIDPolicy struct {
+	// Header is the request header the ID is set on, DefaultRequestIDHeader if empty
+	Header string
+	// TrustIncoming keeps an ID the client sent in Header instead of replacing it with the API Gateway request ID
+	TrustIncoming bool
+	// Echo sets Header on the response unless the handler already did
+	Echo bool
+}
+
+// RequestIDs holds the IDs of the request that produced an http.Request
+type RequestIDs struct {
+	// APIGateway is the ID API Gateway assigned to the request, requestContext.requestId in the event
+	APIGateway string
+	// Lambda is the ID of the Lambda invocation, the AwsRequestID of the lambdacontext
+	Lambda string
+	// Request is the ID in the request ID header once the RequestIDPolicy was applied
+	Request string
+
+	// incoming is what the client sent in DefaultRequestIDHeader, kept so a RequestIDPolicy can undo the default one
+	incoming string
+}
+
+type requestIDContextKey struct{}
+
+// WithRequestIDPolicy is an option function to configure how request IDs are handled, see RequestIDPolicy. By default
+// the API Gateway request ID replaces any X-Request-Id the client sent and isn't echoed.
+func WithRequestIDPolicy(p RequestIDPolicy) func(*Shim) {
+	return func(s *Shim) {
+		s.RequestIDPolicy = &p
+	}
+}
+
+// RequestIDsFromContext returns the request IDs of the request that produced ctx. Outside of a converted request only
+// the Lambda request ID is known.
+func RequestIDsFromContext(ctx context.Context) (RequestIDs, bool) {
+	if ids, ok := ctx.Value(requestIDContextKey{}).(RequestIDs); ok {
+		return ids, true
+	}
+
+	return RequestIDs{Lambda: lambdaRequestID(ctx)}, false
+}
+
+// contextWithRequestIDs adds the request IDs of an event to ctx so log lines can include them before the event is
+// converted
+func contextWithRequestIDs(ctx context.Context, apiGatewayRequestID string) context.Context {
+	ids := RequestIDs{APIGateway: apiGatewayRequestID, Lambda: lambdaRequestID(ctx)}
+	if ids.APIGateway == "" && ids.Lambda == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, requestIDContextKey{}, ids)
+}
+
+// setRequestID applies the default RequestIDPolicy to a converted request. Requests without any ID, e.g. events built
+// by hand in tests, are left alone.
+func setRequestID(req *http.Request, apiGatewayRequestID string) *http.Request {
+	ids := RequestIDs{
+		APIGateway: apiGatewayRequestID,
+		Lambda:     lambdaRequestID(req.Context()),
+		incoming:   req.Header.Get(DefaultRequestIDHeader),
+	}
+	if ids.APIGateway == "" && ids.Lambda == "" {
+		return req
+	}
+
+	return RequestIDPolicy{}.apply(req, ids)
+}
+
+func (p RequestIDPolicy) header() string {
+	if p.Header == "" {
+		return DefaultRequestIDHeader
+	}
+
+	return p.Header
+}
+
+func (p RequestIDPolicy) apply(req *http.Request, ids RequestIDs) *http.Request {
+	header := p.header()
+
+	id := ids.APIGateway
+	if id == "" {
+		id = ids.Lambda
+	}
+	if incoming := req.Header.Get(header); p.TrustIncoming && incoming != "" {
+		id = incoming
+	}
+
+	if id != "" {
+		req.Header.Set(header, id)
+	}
+	ids.Request = id
+
+	return req.WithContext(context.WithValue(req.Context(), requestIDContextKey{}, ids))
+}
+
+// applyRequestIDPolicy replaces the default request ID policy the converters apply with the configured one
+func (s *Shim) applyRequestIDPolicy(req *http.Request) *http.Request {
+	if s.RequestIDPolicy == nil {
+		return req
+	}
+
+	ids, ok := RequestIDsFromContext(req.Context())
+	if !ok {
+		ids.incoming = req.Header.Get(DefaultRequestIDHeader)
+	}
+
+	if ids.incoming == "" {
+		req.Header.Del(DefaultRequestIDHeader)
+	} else {
+		req.Header.Set(DefaultRequestIDHeader, ids.incoming)
+	}
+
+	return s.RequestIDPolicy.apply(req, ids)
+}
+
+// echoRequestID sets the request ID on the response if the policy asks for it
+func (s *Shim) echoRequestID(req *http.Request, h http.Header) {
+	if s.RequestIDPolicy == nil || !s.RequestIDPolicy.Echo {
+		return
+	}
+
+	ids, _ := RequestIDsFromContext(req.Context())
+	if header := s.RequestIDPolicy.header(); ids.Request != "" && h.Get(header) == "" {
+		h.Set(header, ids.Request)
+	}
+}
+
+func lambdaRequestID(ctx context.Context) string {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		return lc.AwsRequestID
+	}
+
+	return ""
+}
+
+// logPrefix returns the request IDs known in ctx formatted for the start of a log line
+func logPrefix(ctx context.Context) string {
+	ids, _ := RequestIDsFromContext(ctx)
+
+	var b strings.Builder
+	if ids.Lambda != "" {
+		b.WriteString("lambda_request_id=" + ids.Lambda + " ")
+	}
+	if ids.APIGateway != "" {
+		b.WriteString("apigw_request_id=" + ids.APIGateway + " ")
+	}
+	if ids.Request != "" && ids.Request != ids.APIGateway && ids.Request != ids.Lambda {
+		b.WriteString("request_id=" + ids.Request + " ")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package shim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+type captureLog struct {
+	lines []string
+}
+
+func (l *captureLog) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func lambdaContext(requestID string) context.Context {
+	return lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: requestID})
+}
+
+// requestIDHandler responds with the request ID header it received and the IDs in the request context
+func requestIDHandler(header string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids, _ := RequestIDsFromContext(r.Context())
+		w.Header().Set("X-Seen", r.Header.Get(header))
+		w.Header().Set("X-Ids", ids.APIGateway+"|"+ids.Lambda+"|"+ids.Request)
+	})
+}
+
+func TestRequestIDDefaultPolicy(t *testing.T) {
+	s := New(requestIDHandler(DefaultRequestIDHeader))
+
+	rest := restRequest(http.MethodGet, "/")
+	rest.Headers = map[string]string{"X-Request-Id": "from-client"}
+	rest.RequestContext.RequestID = "apigw-rest"
+
+	v1, err := s.HandleHttpApiV1Requests(lambdaContext("lambda-1"), rest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1.MultiValueHeaders["X-Seen"][0] != "apigw-rest" || v1.MultiValueHeaders["X-Ids"][0] != "apigw-rest|lambda-1|apigw-rest" {
+		t.Errorf("expected the API Gateway ID to replace the client's on v1 events but got %v", v1.MultiValueHeaders)
+	}
+	if _, ok := v1.MultiValueHeaders[DefaultRequestIDHeader]; ok {
+		t.Error("expected the request ID not to be echoed by default")
+	}
+
+	v2 := v2Request(http.MethodGet, "/", map[string]string{"x-request-id": "from-client"})
+	v2.RequestContext.RequestID = "apigw-v2"
+
+	resp, err := s.HandleHttpApiRequests(lambdaContext("lambda-2"), v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Headers["X-Seen"] != "apigw-v2" || resp.Headers["X-Ids"] != "apigw-v2|lambda-2|apigw-v2" {
+		t.Errorf("expected the API Gateway ID to replace the client's on v2 events but got %v", resp.Headers)
+	}
+}
+
+func TestRequestIDFallsBackToLambdaRequestID(t *testing.T) {
+	resp, err := New(requestIDHandler(DefaultRequestIDHeader)).Handle(lambdaContext("lambda-1"), restRequest(http.MethodGet, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Headers["X-Seen"] != "lambda-1" {
+		t.Errorf("expected the Lambda request ID without an API Gateway ID but got %q", resp.Headers["X-Seen"])
+	}
+}
+
+func TestRequestIDPolicy(t *testing.T) {
+	s := New(requestIDHandler("X-Correlation-Id"), WithRequestIDPolicy(RequestIDPolicy{
+		Header:        "X-Correlation-Id",
+		TrustIncoming: true,
+		Echo:          true,
+	}))
+
+	v2 := v2Request(http.MethodGet, "/", map[string]string{"x-correlation-id": "from-client", "x-request-id": "other"})
+	v2.RequestContext.RequestID = "apigw-v2"
+
+	resp, err := s.HandleHttpApiRequests(lambdaContext("lambda-1"), v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Headers["X-Seen"] != "from-client" || resp.Headers["X-Ids"] != "apigw-v2|lambda-1|from-client" {
+		t.Errorf("expected the trusted incoming ID but got %v", resp.Headers)
+	}
+	if resp.Headers["X-Correlation-Id"] != "from-client" {
+		t.Errorf("expected the request ID to be echoed but got %v", resp.Headers)
+	}
+
+	v2.Headers = nil
+	resp, err = s.HandleHttpApiRequests(lambdaContext("lambda-1"), v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Headers["X-Correlation-Id"] != "apigw-v2" {
+		t.Errorf("expected the API Gateway ID without an incoming one but got %v", resp.Headers)
+	}
+}
+
+func TestRequestIDPolicyDoesNotTrustIncomingByDefault(t *testing.T) {
+	s := New(requestIDHandler(DefaultRequestIDHeader), WithRequestIDPolicy(RequestIDPolicy{Echo: true}))
+
+	rest := restRequest(http.MethodGet, "/")
+	rest.Headers = map[string]string{"X-Request-Id": "from-client"}
+	rest.RequestContext.RequestID = "apigw-rest"
+
+	resp, err := s.Handle(context.Background(), rest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Headers["X-Seen"] != "apigw-rest" || resp.Headers[DefaultRequestIDHeader] != "apigw-rest" {
+		t.Errorf("expected the API Gateway ID to be used and echoed but got %v", resp.Headers)
+	}
+}
+
+func TestRequestIDLogPrefix(t *testing.T) {
+	l := &captureLog{}
+	s := New(requestIDHandler(DefaultRequestIDHeader), SetDebugLogger(l))
+
+	rest := restRequest(http.MethodGet, "/")
+	rest.RequestContext.RequestID = "apigw-rest"
+
+	if _, err := s.Handle(lambdaContext("lambda-1"), rest); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l.lines) == 0 {
+		t.Fatal("expected log lines")
+	}
+	for _, line := range l.lines {
+		if !strings.HasPrefix(line, "lambda_request_id=lambda-1 apigw_request_id=apigw-rest ") {
+			t.Errorf("expected both request IDs on every log line but got %q", line)
+		}
+	}
+}
+
+func TestRequestIDLogPrefixIsNotAFormat(t *testing.T) {
+	l := &captureLog{}
+	s := New(requestIDHandler(DefaultRequestIDHeader), SetDebugLogger(l), WithRequestIDPolicy(RequestIDPolicy{TrustIncoming: true}))
+
+	rest := restRequest(http.MethodGet, "/")
+	rest.Headers = map[string]string{"X-Request-Id": "%s%d%v"}
+	rest.RequestContext.RequestID = "apigw-rest"
+
+	if _, err := s.Handle(lambdaContext("lambda-1"), rest); err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, line := range l.lines {
+		if strings.Contains(line, "%!") {
+			t.Errorf("expected the request ID not to be used as a format but got %q", line)
+		}
+		if strings.Contains(line, "request_id=%s%d%v ") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the incoming request ID on the log lines but got %q", l.lines)
+	}
+}
+
+func TestRequestIDEchoedOnReplacedResponses(t *testing.T) {
+	offloader := NewOffloader(NewMemoryBlobStore("https://blobs.example.com"))
+	offloader.Threshold = 100
+
+	replace := func(req *http.Request, rw *ResponseWriter, o Overflow) {
+		rw.Headers = make(http.Header)
+		rw.Body.Reset()
+		rw.Code = http.StatusBadGateway
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 200)))
+	})
+
+	rest := restRequest(http.MethodGet, "/")
+	rest.RequestContext.RequestID = "apigw-rest"
+
+	for name, s := range map[string]*Shim{
+		"offloaded":   New(handler, WithRequestIDPolicy(RequestIDPolicy{Echo: true}), WithOffloader(offloader)),
+		"overflowing": New(handler, WithRequestIDPolicy(RequestIDPolicy{Echo: true}), WithResponseLimit(100, replace)),
+	} {
+		resp, err := s.Handle(context.Background(), rest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode == http.StatusOK || resp.Headers[DefaultRequestIDHeader] != "apigw-rest" {
+			t.Errorf("expected the request ID to be echoed on the %v response but got %v %v", name, resp.StatusCode, resp.Headers)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package shim
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// urlFromRawPath returns a URL for a path as the client sent it, percent-encoding included. RawPath is only kept when
+// it differs from the default encoding of Path, the same as url.Parse does.
+func urlFromRawPath(raw string) (*url.URL, error) {
+	if raw == "" {
+		raw = "/"
+	}
+
+	path, err := url.PathUnescape(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return urlFromPaths(path, raw), nil
+}
+
+// urlFromRestPath returns a URL for the path of a REST style event. The path is decoded by API Gateway, so the raw
+// form is looked up in the request context path, which is what the client sent including any stage or base path. The
+// decoded path is used as is when no raw form is found. prefix is what the client sent in front of the path, e.g. the
+// stage on the execute-api endpoint.
+func urlFromRestPath(path, contextPath string) (u *url.URL, prefix string) {
+	if path == "" {
+		path = "/"
+	}
+
+	for i := 0; i < len(contextPath); i++ {
+		if contextPath[i] != '/' {
+			continue
+		}
+
+		suffix := contextPath[i:]
+		if decoded, err := url.PathUnescape(suffix); err == nil && decoded == path {
+			return urlFromPaths(path, suffix), contextPath[:i]
+		}
+	}
+
+	return &url.URL{Path: path}, ""
+}
+
+func urlFromPaths(path, raw string) *url.URL {
+	u := &url.URL{Path: path}
+	if u.EscapedPath() != raw {
+		u.RawPath = raw
+		// EscapedPath ignores RawPath if it isn't a valid encoding of Path, e.g. when it holds raw unicode
+		if u.EscapedPath() != raw {
+			u.RawPath = ""
+		}
+	}
+
+	return u
+}
+
+// setRequestURL points req at u and sets RequestURI to the request target the way net/http.Server does. The URL is
+// assigned directly since a path such as "//a" would be taken for a host if it went through url.Parse.
+func setRequestURL(req *http.Request, u *url.URL) {
+	req.URL = u
+
+	var b strings.Builder
+	b.WriteString(u.EscapedPath())
+	if u.RawQuery != "" {
+		b.WriteString("?")
+		b.WriteString(u.RawQuery)
+	}
+	req.RequestURI = b.String()
+}
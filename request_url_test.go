@@ -0,0 +1,121 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var trickyPaths = []struct {
+	name       string
+	raw        string
+	decoded    string
+	rawPath    string
+	requestURI string
+}{
+	{"plain", "/orders/42", "/orders/42", "", "/orders/42"},
+	{"encoded slash", "/files/a%2Fb", "/files/a/b", "/files/a%2Fb", "/files/a%2Fb"},
+	{"encoded unicode", "/caf%C3%A9", "/café", "", "/caf%C3%A9"},
+	{"plus", "/a+b", "/a+b", "", "/a+b"},
+	{"encoded space", "/a%20b", "/a b", "", "/a%20b"},
+	{"double slash", "//orders//42", "//orders//42", "", "//orders//42"},
+	{"dot dot", "/static/../secret", "/static/../secret", "", "/static/../secret"},
+	{"encoded percent", "/100%25", "/100%", "", "/100%25"},
+}
+
+func TestV2RequestPaths(t *testing.T) {
+	for _, test := range trickyPaths {
+		t.Run(test.name, func(t *testing.T) {
+			event := v2Request(http.MethodGet, test.raw, nil)
+			event.RawQueryString = "q=1"
+
+			req, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(context.Background(), event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertRequestPath(t, req, test.decoded, test.rawPath, test.requestURI+"?q=1")
+		})
+	}
+}
+
+func TestRestRequestPaths(t *testing.T) {
+	for _, test := range trickyPaths {
+		t.Run(test.name, func(t *testing.T) {
+			event := events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodGet,
+				Path:       test.decoded,
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Path: "/prod" + test.raw,
+				},
+			}
+
+			req, err := NewHttpRequestFromAPIGatewayProxyRequest(context.Background(), event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertRequestPath(t, req, test.decoded, test.rawPath, test.requestURI)
+		})
+	}
+}
+
+func TestRestRequestPathWithoutContextPath(t *testing.T) {
+	req, err := NewHttpRequestFromAPIGatewayProxyRequest(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/100%",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRequestPath(t, req, "/100%", "", "/100%25")
+}
+
+func TestHttpApiV1RequestPathFromContext(t *testing.T) {
+	req, err := NewHttpRequestFromHttpApiV1Request(context.Background(), events.APIGatewayProxyRequest{
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod: http.MethodGet,
+			Path:       "/files/a%2Fb",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRequestPath(t, req, "/files/a/b", "/files/a%2Fb", "/files/a%2Fb")
+}
+
+func assertRequestPath(t *testing.T, req *http.Request, path, rawPath, requestURI string) {
+	t.Helper()
+
+	if req.URL.Path != path {
+		t.Errorf("expected Path to be %q but was %q", path, req.URL.Path)
+	}
+	if req.URL.RawPath != rawPath {
+		t.Errorf("expected RawPath to be %q but was %q", rawPath, req.URL.RawPath)
+	}
+	if req.RequestURI != requestURI {
+		t.Errorf("expected RequestURI to be %q but was %q", requestURI, req.RequestURI)
+	}
+	if req.URL.Host != req.Host {
+		t.Errorf("expected the path not to be mistaken for a host but URL.Host was %q", req.URL.Host)
+	}
+}
+
+func TestEncodedSlashReachesHandler(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.EscapedPath() + " " + r.RequestURI))
+	})
+
+	resp, err := New(h).HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/files/a%2Fb", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Body != "/files/a%2Fb /files/a%2Fb" {
+		t.Errorf("expected the encoded slash to reach the handler but got %q", resp.Body)
+	}
+}
@@ -0,0 +1,161 @@
+package shim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	// MaxResponsePayloadSize is the largest payload a synchronously invoked Lambda function can return
+	MaxResponsePayloadSize = 6 * 1024 * 1024
+
+	// DefaultResponseLimit leaves room in the payload for the JSON envelope around the headers and body
+	DefaultResponseLimit = MaxResponsePayloadSize - 4*1024
+
+	// headerOverhead approximates the JSON quoting and separators around each header
+	headerOverhead = 6
+
+	httpHeaderWarning = "Warning"
+)
+
+// Overflow describes a response whose encoded payload is larger than the response limit
+type Overflow struct {
+	// Limit is the maximum payload size in bytes
+	Limit int
+	// Size is the approximate size of the payload in bytes, including headers
+	Size int
+	// Base64Encoded reports whether the body will be base64 encoded in the payload
+	Base64Encoded bool
+}
+
+// OverflowPolicy is applied to a response that is larger than the response limit. It may rewrite rw in any way, which
+// also makes it the hook for custom handling such as storing the body elsewhere.
+type OverflowPolicy func(req *http.Request, rw *ResponseWriter, o Overflow)
+
+// ResponseLimit configures the largest response shim hands back to Lambda and what happens to responses over it
+type ResponseLimit struct {
+	MaxSize int
+	Policy  OverflowPolicy
+}
+
+// WithResponseLimit is an option function to set the response size limit and the policy applied to responses over it.
+// By default responses over DefaultResponseLimit are replaced with a 502.
+func WithResponseLimit(maxSize int, policy OverflowPolicy) func(*Shim) {
+	return func(s *Shim) {
+		s.ResponseLimit = &ResponseLimit{
+			MaxSize: maxSize,
+			Policy:  policy,
+		}
+	}
+}
+
+// RejectOverflow returns an OverflowPolicy that replaces the response with a plain text error using code, e.g.
+// http.StatusBadGateway or http.StatusRequestEntityTooLarge. Headers that don't describe the body, e.g. CORS headers,
+// are kept.
+func RejectOverflow(code int) OverflowPolicy {
+	return func(req *http.Request, rw *ResponseWriter, o Overflow) {
+		rw.discardEntity()
+
+		rw.Header().Set(httpHeaderContentType, "text/plain; charset=utf-8")
+		rw.WriteHeader(code)
+		fmt.Fprintf(rw, "response of %d bytes exceeds the Lambda response size limit of %d bytes\n", o.Size, o.Limit)
+	}
+}
+
+// TruncateOverflow is an OverflowPolicy that cuts the body down to fit the limit and flags the response with a
+// Warning header. Text bodies are cut on a UTF-8 boundary. Content-encoded bodies, e.g. from WithCompression, can't be
+// cut without corrupting them, so they are rejected with a 502 like RejectOverflow does.
+func TruncateOverflow(req *http.Request, rw *ResponseWriter, o Overflow) {
+	if isContentEncoded(rw.Headers) {
+		RejectOverflow(http.StatusBadGateway)(req, rw, o)
+		return
+	}
+
+	warning := `199 shim "response truncated from ` + strconv.Itoa(rw.Body.Len()) + ` bytes"`
+	budget := o.Limit - (o.Size - rw.EncodedSize(o.Base64Encoded)) - len(httpHeaderWarning) - len(warning) - headerOverhead
+	if budget < 0 {
+		budget = 0
+	}
+
+	body := rw.Body.Bytes()
+	var keep int
+	if o.Base64Encoded {
+		keep = budget / 4 * 3
+	} else {
+		keep = jsonEscapedPrefix(body, budget)
+	}
+
+	rw.Body.Truncate(keep)
+	rw.jsonOverhead = jsonEscapeOverhead(rw.Body.Bytes())
+	rw.Headers.Del(contentLength)
+	rw.Headers.Add(httpHeaderWarning, warning)
+}
+
+// enforce applies the overflow policy if the response payload is over the limit
+func (l *ResponseLimit) enforce(req *http.Request, rw *ResponseWriter, base64Encoded bool) bool {
+	size := payloadSize(rw, base64Encoded)
+	if size <= l.MaxSize {
+		return false
+	}
+
+	policy := l.Policy
+	if policy == nil {
+		policy = RejectOverflow(http.StatusBadGateway)
+	}
+
+	policy(req, rw, Overflow{Limit: l.MaxSize, Size: size, Base64Encoded: base64Encoded})
+	return true
+}
+
+// payloadSize approximates the size of the Lambda response payload for rw
+func payloadSize(rw *ResponseWriter, base64Encoded bool) int {
+	size := rw.EncodedSize(base64Encoded)
+	for k, vs := range rw.Headers {
+		for _, v := range vs {
+			size += len(k) + len(v) + headerOverhead
+		}
+	}
+
+	return size
+}
+
+// jsonEscapedPrefix returns the length of the longest prefix of b that fits in budget bytes once escaped into a JSON
+// string, without splitting a UTF-8 sequence
+func jsonEscapedPrefix(b []byte, budget int) int {
+	used := 0
+	for i := 0; i < len(b); {
+		_, n := utf8.DecodeRune(b[i:])
+		size := n + jsonEscapeOverhead(b[i:i+n])
+		if used+size > budget {
+			return i
+		}
+
+		used += size
+		i += n
+	}
+
+	return len(b)
+}
+
+// jsonEscapeOverhead returns the number of extra bytes b needs when escaped into a JSON string by encoding/json with
+// HTML escaping disabled, which is how the Lambda runtime marshals responses
+func jsonEscapeOverhead(b []byte) int {
+	overhead := 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '"' || c == '\\' || c == '\n' || c == '\r' || c == '\t':
+			overhead++
+		case c < 0x20:
+			overhead += 5
+		case c == 0xe2 && i+2 < len(b) && b[i+1] == 0x80 && (b[i+2] == 0xa8 || b[i+2] == 0xa9):
+			// U+2028 and U+2029 are always escaped as six byte \u sequences
+			overhead += 3
+			i += 2
+		}
+	}
+
+	return overhead
+}
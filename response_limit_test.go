@@ -0,0 +1,179 @@
+package shim
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestResponseWriterEncodedSize(t *testing.T) {
+	rw := NewResponseWriter()
+	rw.Write([]byte("a\"b\n"))
+	rw.Write([]byte{0x01})
+	rw.Write([]byte(" "))
+
+	body, _ := json.Marshal(rw.Body.String())
+	if size := rw.EncodedSize(false); size != len(body)-2 {
+		t.Errorf("expected escaped size to be %v but was %v", len(body)-2, size)
+	}
+
+	if size := rw.EncodedSize(true); size != base64.StdEncoding.EncodedLen(rw.Body.Len()) {
+		t.Errorf("expected base64 size to be %v but was %v", base64.StdEncoding.EncodedLen(rw.Body.Len()), size)
+	}
+}
+
+func TestHandleRejectsOversizedResponsesByDefault(t *testing.T) {
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(httpHeaderContentType, "text/plain")
+		w.Write([]byte(strings.Repeat("a", DefaultResponseLimit)))
+	}))
+
+	resp, err := s.Handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected status code 502 but was %v", resp.StatusCode)
+	}
+
+	if !strings.Contains(resp.Body, "exceeds the Lambda response size limit") {
+		t.Errorf("expected an explanatory body but was %v", resp.Body)
+	}
+}
+
+func TestRejectOverflowKeepsNonEntityHeaders(t *testing.T) {
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "https://app.example.com")
+		w.Header().Set(DefaultRequestIDHeader, "req-1")
+		w.Header().Set(httpHeaderContentType, "text/csv")
+		w.Header().Set(httpHeaderETag, `"v1"`)
+		w.Write([]byte(strings.Repeat("a", 200)))
+	}), WithResponseLimit(100, RejectOverflow(http.StatusRequestEntityTooLarge)))
+
+	resp, err := s.Handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status code 413 but was %v", resp.StatusCode)
+	}
+
+	if resp.Headers["Access-Control-Allow-Origin"] != "https://app.example.com" || resp.Headers[DefaultRequestIDHeader] != "req-1" {
+		t.Errorf("expected the CORS and request ID headers to be kept but got %v", resp.Headers)
+	}
+
+	if resp.Headers[httpHeaderContentType] != "text/plain; charset=utf-8" || resp.Headers[httpHeaderETag] != "" {
+		t.Errorf("expected the headers of the rejected body to be replaced but got %v", resp.Headers)
+	}
+}
+
+func TestResponseLimitAccountsForBase64Inflation(t *testing.T) {
+	limit := 1000
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(httpHeaderContentType, "application/octet-stream")
+		w.Write(make([]byte, 900))
+	}), WithResponseLimit(limit, RejectOverflow(http.StatusRequestEntityTooLarge)))
+
+	resp, err := s.Handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status code 413 but was %v", resp.StatusCode)
+	}
+}
+
+func TestTruncateOverflow(t *testing.T) {
+	limit := 500
+	body := strings.Repeat("é\"", 400)
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(httpHeaderContentType, "text/plain; charset=utf-8")
+		w.Write([]byte(body))
+	}), WithResponseLimit(limit, TruncateOverflow))
+
+	resp, err := s.HandleHttpApiRequests(context.Background(), events.APIGatewayV2HTTPRequest{
+		RawPath: "/",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code to be kept but was %v", resp.StatusCode)
+	}
+
+	if resp.IsBase64Encoded || !utf8.ValidString(resp.Body) || !strings.HasPrefix(body, resp.Body) {
+		t.Error("expected body to be truncated on a UTF-8 boundary")
+	}
+
+	if resp.Headers[httpHeaderWarning] == "" {
+		t.Error("expected a Warning header on truncated responses")
+	}
+
+	payload, _ := json.Marshal(resp)
+	if len(payload) > limit+200 {
+		t.Errorf("expected payload to be close to the limit but was %v bytes", len(payload))
+	}
+}
+
+func TestTruncateOverflowRejectsCompressedResponses(t *testing.T) {
+	// compress/flate emits a stored block for some random hex bodies, which makes the gzip output larger than the body
+	// and leaves it uncompressed. This seed gzips to about 4KB, so the response is always encoded and over the limit.
+	random := make([]byte, 4000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(httpHeaderContentType, "text/plain; charset=utf-8")
+		w.Write([]byte(hex.EncodeToString(random)))
+	}), WithCompression(10), WithResponseLimit(2000, TruncateOverflow))
+
+	resp, err := s.HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/", map[string]string{"accept-encoding": "gzip"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected a compressed response over the limit to be rejected but was %v", resp.StatusCode)
+	}
+
+	if resp.Headers[httpHeaderContentEncoding] != "" || resp.IsBase64Encoded {
+		t.Errorf("expected the rejection not to claim a content encoding but got %v", resp.Headers)
+	}
+}
+
+func TestOverflowPolicyHook(t *testing.T) {
+	var overflow Overflow
+	hook := func(req *http.Request, rw *ResponseWriter, o Overflow) {
+		overflow = o
+		rw.discardEntity()
+		rw.WriteHeader(http.StatusSeeOther)
+	}
+
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 200)))
+	}), WithResponseLimit(100, hook))
+
+	resp, _ := s.Handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("expected hook to replace the response but was %v", resp.StatusCode)
+	}
+
+	if overflow.Limit != 100 || overflow.Size <= 200 || overflow.Base64Encoded {
+		t.Errorf("unexpected overflow %+v", overflow)
+	}
+}
@@ -1,13 +1,18 @@
 package shim
 
 import (
+	"encoding/base64"
 	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 const (
 	httpHeaderContentType  = "Content-Type"
+	httpHeaderCookie       = "Cookie"
+	httpHeaderSetCookie    = "Set-Cookie"
 	multipleValueSeperator = ","
 	prefixText             = "text/"
 )
@@ -24,7 +29,7 @@ var (
 // formatHeaders converts an http.Headers map into a map[string]string. If there are multiple values for a key
 // in the http.Headers they are combined together with "," per RFC 2616
 func formatHeaders(h http.Header) map[string]string {
-	headers := make(map[string]string)
+	headers := make(map[string]string, len(h))
 
 	for k, v := range h {
 		// e.g. convert accept-encoding into Accept-Encoding
@@ -70,3 +75,48 @@ func setContentTypeIfNotPresent(headers http.Header, body []byte) {
 		headers.Set("Content-Type", http.DetectContentType(body))
 	}
 }
+
+// setContentLength sets Content-Length to the length of the body as the client receives it, i.e. before any base64
+// encoding for the Lambda payload. 1xx, 204 and 304 responses are left alone, as are HEAD responses that carry the
+// length of the body they would have had.
+func setContentLength(rw *ResponseWriter) {
+	if (rw.Code != 0 && rw.Code < 200) || rw.Code == http.StatusNoContent || rw.Code == http.StatusNotModified {
+		return
+	}
+
+	if rw.Body.Len() == 0 && rw.Headers.Get(contentLength) != "" {
+		return
+	}
+
+	rw.Headers.Set(contentLength, strconv.Itoa(rw.Body.Len()))
+}
+
+// isSuccess reports whether code is a 2xx status code
+func isSuccess(code int) bool {
+	return code >= 200 && code < 300
+}
+
+// restBodyIsBase64 reports whether a REST API response body is sent base64 encoded. REST APIs decide by the
+// Content-Type sniffed from the body, see NewAPIGatewayProxyResponse.
+func restBodyIsBase64(h http.Header, body []byte) bool {
+	return isContentEncoded(h) || shouldConvertToBase64(http.DetectContentType(body))
+}
+
+// httpApiBodyIsBase64 reports whether an HTTP API response body is sent base64 encoded. HTTP APIs decode any base64 body,
+// so only bodies that can't be sent as a JSON string are encoded.
+func httpApiBodyIsBase64(h http.Header, body []byte) bool {
+	return isContentEncoded(h) || !utf8.Valid(body)
+}
+
+// encodeBase64 returns the standard base64 encoding of b. Unlike base64.StdEncoding.EncodeToString it encodes straight
+// into the returned string instead of copying an intermediate buffer, which matters for multi-megabyte bodies.
+func encodeBase64(b []byte) string {
+	var sb strings.Builder
+	sb.Grow(base64.StdEncoding.EncodedLen(len(b)))
+
+	enc := base64.NewEncoder(base64.StdEncoding, &sb)
+	enc.Write(b)
+	enc.Close()
+
+	return sb.String()
+}
@@ -2,11 +2,52 @@ package shim
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 )
 
 var headerContentType = "Content-Type"
 
+// maxPooledBodySize keeps buffers grown past the Lambda response limit, e.g. by responses that get offloaded, from
+// being pinned in the pool for the life of the execution environment
+const maxPooledBodySize = MaxResponsePayloadSize
+
+var responseWriterPool = sync.Pool{
+	New: func() interface{} {
+		return NewResponseWriter()
+	},
+}
+
+// acquireResponseWriter returns an empty ResponseWriter from the pool whose writes are bound by the deadline of ctx.
+// Shim hands these to the handler and returns them with releaseResponseWriter once the response has been converted, so
+// handlers must not hold on to the ResponseWriter after ServeHTTP returns.
+func acquireResponseWriter(ctx context.Context) *ResponseWriter {
+	rw := responseWriterPool.Get().(*ResponseWriter)
+	rw.invocationDeadline, _ = ctx.Deadline()
+	return rw
+}
+
+// releaseResponseWriter clears rw and puts it back in the pool
+func releaseResponseWriter(rw *ResponseWriter) {
+	if rw.Body.Cap() > maxPooledBodySize {
+		return
+	}
+
+	rw.Code = 0
+	clear(rw.Headers)
+	rw.Body.Reset()
+	rw.jsonOverhead = 0
+	rw.invocationDeadline = time.Time{}
+	rw.writeDeadline = time.Time{}
+
+	responseWriterPool.Put(rw)
+}
+
 // NewResponseWriter returns an ResponseWriter with the headers properly initialized
 func NewResponseWriter() *ResponseWriter {
 	return &ResponseWriter{
@@ -20,6 +61,13 @@ type ResponseWriter struct {
 	Code    int
 	Headers http.Header
 	Body    bytes.Buffer
+
+	// jsonOverhead counts the extra bytes the body needs when it is escaped into a JSON string
+	jsonOverhead int
+
+	// invocationDeadline is when the Lambda invocation times out, writeDeadline is set through SetWriteDeadline
+	invocationDeadline time.Time
+	writeDeadline      time.Time
 }
 
 // Header adheres the http.ResponseWriter interface
@@ -29,6 +77,10 @@ func (rw *ResponseWriter) Header() http.Header {
 
 // Write adheres to the io.Writer interface
 func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	if rw.deadlineExceeded() {
+		return 0, os.ErrDeadlineExceeded
+	}
+
 	if rw.Code == 0 {
 		rw.WriteHeader(http.StatusOK)
 	}
@@ -37,10 +89,120 @@ func (rw *ResponseWriter) Write(b []byte) (int, error) {
 		rw.Header().Set(headerContentType, http.DetectContentType(b))
 	}
 
+	rw.jsonOverhead += jsonEscapeOverhead(b)
 	return rw.Body.Write(b)
 }
 
-// WriteHeader adheres to the http.ResponseWriter interface
+// ReadFrom adheres to the io.ReaderFrom interface so io.Copy, and with it http.ServeContent, reads straight into the
+// body buffer instead of going through an intermediate buffer
+func (rw *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if rw.deadlineExceeded() {
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	var n int64
+	if rw.Code == 0 || rw.Header().Get(headerContentType) == "" {
+		// Let Write set the status code and sniff the Content-Type from the first bytes
+		sniff := make([]byte, 512)
+		m, err := io.ReadFull(r, sniff)
+		if m > 0 {
+			rw.Write(sniff[:m])
+			n += int64(m)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+
+	start := rw.Body.Len()
+	m, err := rw.Body.ReadFrom(r)
+	rw.jsonOverhead += jsonEscapeOverhead(rw.Body.Bytes()[start:])
+
+	return n + m, err
+}
+
+// Flush adheres to the http.Flusher interface. Responses are buffered until the handler returns, so flushing only
+// commits the status code.
+func (rw *ResponseWriter) Flush() {
+	if rw.Code == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+}
+
+// FlushError is called by http.ResponseController.Flush
+func (rw *ResponseWriter) FlushError() error {
+	if rw.deadlineExceeded() {
+		return os.ErrDeadlineExceeded
+	}
+
+	rw.Flush()
+	return nil
+}
+
+// SetWriteDeadline is called by http.ResponseController.SetWriteDeadline. Writes after the deadline fail with
+// os.ErrDeadlineExceeded. Writes never outlive the Lambda invocation, so a zero or later deadline falls back to the
+// invocation deadline.
+func (rw *ResponseWriter) SetWriteDeadline(t time.Time) error {
+	rw.writeDeadline = t
+	return nil
+}
+
+// SetReadDeadline is called by http.ResponseController.SetReadDeadline. Request bodies are held in memory and never
+// block, so there is nothing to enforce.
+func (rw *ResponseWriter) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// deadlineExceeded reports whether the earlier of the write deadline and the invocation deadline has passed
+func (rw *ResponseWriter) deadlineExceeded() bool {
+	deadline := rw.writeDeadline
+	if deadline.IsZero() || (!rw.invocationDeadline.IsZero() && rw.invocationDeadline.Before(deadline)) {
+		deadline = rw.invocationDeadline
+	}
+
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+// EncodedSize returns the number of bytes the body takes up in the Lambda response payload, either base64 encoded or
+// escaped into a JSON string. Only bytes written through Write are accounted for when escaping.
+func (rw *ResponseWriter) EncodedSize(base64Encoded bool) int {
+	if base64Encoded {
+		return base64.StdEncoding.EncodedLen(rw.Body.Len())
+	}
+
+	return rw.Body.Len() + rw.jsonOverhead
+}
+
+// entityHeaders describe the body and are dropped along with it when a response is replaced
+var entityHeaders = []string{
+	httpHeaderContentType,
+	contentLength,
+	httpHeaderContentEncoding,
+	httpHeaderContentDisposition,
+	httpHeaderETag,
+	"Last-Modified",
+}
+
+// discardEntity discards the status code, body and the headers describing the body so a replacement response can be
+// written. Other headers such as Set-Cookie or CORS headers are kept.
+func (rw *ResponseWriter) discardEntity() {
+	rw.Code = 0
+	for _, h := range entityHeaders {
+		rw.Headers.Del(h)
+	}
+	rw.Body.Reset()
+	rw.jsonOverhead = 0
+}
+
+// WriteHeader adheres to the http.ResponseWriter interface. Like net/http, only the first status code is used and later
+// calls have no effect.
 func (rw *ResponseWriter) WriteHeader(c int) {
+	if rw.Code != 0 {
+		return
+	}
+
 	rw.Code = c
 }
@@ -1,9 +1,17 @@
 package shim
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestResponseWriterSetCodeAndWrite(t *testing.T) {
@@ -22,3 +30,116 @@ func TestResponseWriterFirstWrite(t *testing.T) {
 		t.Error("expected first write to response writer to set status code")
 	}
 }
+
+func TestReleasedResponseWriterIsEmpty(t *testing.T) {
+	rw := acquireResponseWriter(context.Background())
+	rw.Header().Set("X-Leak", "yes")
+	rw.WriteHeader(http.StatusTeapot)
+	fmt.Fprint(rw, "hello, \"world\"")
+	releaseResponseWriter(rw)
+
+	// Drain the pool until the released writer comes back or a fresh one is handed out
+	for i := 0; i < 10; i++ {
+		rw := acquireResponseWriter(context.Background())
+		if rw.Code != 0 || len(rw.Headers) != 0 || rw.Body.Len() != 0 || rw.EncodedSize(false) != 0 {
+			t.Fatalf("expected pooled response writer to be empty but was %+v", rw)
+		}
+		if rw.Headers == nil {
+			t.Fatal("expected pooled response writer to have a headers map")
+		}
+	}
+}
+
+func TestEncodeBase64(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 1024, 1025, 100000} {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(i * 7)
+		}
+
+		if got, want := encodeBase64(b), base64.StdEncoding.EncodeToString(b); got != want {
+			t.Errorf("expected encodeBase64 of %v bytes to match base64.StdEncoding", n)
+		}
+	}
+}
+
+func TestResponseWriterServeContent(t *testing.T) {
+	body := strings.Repeat("<html>hello, world</html>", 100)
+
+	rw := NewResponseWriter()
+	var _ io.ReaderFrom = rw
+	req := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+	http.ServeContent(rw, req, "", time.Time{}, strings.NewReader(body))
+
+	if rw.Code != http.StatusOK {
+		t.Errorf("expected status code to be %v but was %v", http.StatusOK, rw.Code)
+	}
+	if rw.Body.String() != body {
+		t.Errorf("expected body to be copied in full but got %v bytes", rw.Body.Len())
+	}
+	if ct := rw.Headers.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("expected sniffed Content-Type but was %q", ct)
+	}
+}
+
+func TestResponseWriterReadFromAccountsForEscaping(t *testing.T) {
+	rw := NewResponseWriter()
+	rw.Header().Set("Content-Type", "application/json")
+	rw.ReadFrom(strings.NewReader(`{"a":"b"}`))
+
+	if rw.EncodedSize(false) != len(`{\"a\":\"b\"}`) {
+		t.Errorf("expected escaped size to be counted but was %v", rw.EncodedSize(false))
+	}
+}
+
+func TestResponseWriterFlush(t *testing.T) {
+	rw := NewResponseWriter()
+	var f http.Flusher = rw
+	f.Flush()
+
+	if rw.Code != http.StatusOK {
+		t.Errorf("expected flush to commit the status code but was %v", rw.Code)
+	}
+
+	rw.WriteHeader(http.StatusInternalServerError)
+	if rw.Code != http.StatusOK {
+		t.Errorf("expected WriteHeader after flush to have no effect but the status code was %v", rw.Code)
+	}
+}
+
+func TestResponseControllerWriteDeadline(t *testing.T) {
+	rw := NewResponseWriter()
+	rc := http.NewResponseController(rw)
+
+	if err := rc.Flush(); err != nil {
+		t.Fatalf("expected flush to be supported but got %v", err)
+	}
+	if err := rc.SetWriteDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("expected write deadline to be supported but got %v", err)
+	}
+
+	if _, err := fmt.Fprint(rw, "too late"); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected write after the deadline to fail but got %v", err)
+	}
+
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fmt.Fprint(rw, "on time"); err != nil {
+		t.Errorf("expected clearing the deadline to allow writes but got %v", err)
+	}
+}
+
+func TestResponseWriterInvocationDeadline(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Millisecond))
+	defer cancel()
+
+	rw := acquireResponseWriter(ctx)
+	defer releaseResponseWriter(rw)
+
+	// A later write deadline can't extend the invocation
+	http.NewResponseController(rw).SetWriteDeadline(time.Now().Add(time.Hour))
+	if _, err := fmt.Fprint(rw, "too late"); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected write after the invocation deadline to fail but got %v", err)
+	}
+}
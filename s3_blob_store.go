@@ -0,0 +1,125 @@
+package shim
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// S3BlobStore is a BlobStore backed by an S3 bucket. Requests are signed with the credentials of the Lambda
+// execution role, which needs s3:PutObject and s3:GetObject on the bucket.
+type S3BlobStore struct {
+	Bucket string
+	Region string
+	// Endpoint overrides the virtual-hosted bucket endpoint, e.g. for S3 compatible stores
+	Endpoint    string
+	Credentials func() AWSCredentials
+	Client      *http.Client
+	// now is swapped out in tests
+	now func() time.Time
+}
+
+// NewS3BlobStore returns an S3BlobStore for bucket. The region defaults to the region the function runs in.
+func NewS3BlobStore(bucket, region string) *S3BlobStore {
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+
+	return &S3BlobStore{
+		Bucket:      bucket,
+		Region:      region,
+		Credentials: AWSCredentialsFromEnv,
+		Client:      http.DefaultClient,
+	}
+}
+
+// Put uploads body to key with a PutObject request
+func (s *S3BlobStore) Put(ctx context.Context, key string, body []byte, meta BlobMetadata) error {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if meta.ContentType != "" {
+		req.Header.Set(httpHeaderContentType, meta.ContentType)
+	}
+	if meta.ContentDisposition != "" {
+		req.Header.Set(httpHeaderContentDisposition, meta.ContentDisposition)
+	}
+	if meta.ContentEncoding != "" {
+		req.Header.Set(httpHeaderContentEncoding, meta.ContentEncoding)
+	}
+
+	s.signer().sign(req, sha256Hex(body), s.time())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 put object returned %v: %s", resp.StatusCode, msg)
+	}
+
+	return nil
+}
+
+// PresignGet returns a presigned GetObject URL for key
+func (s *S3BlobStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+
+	return s.signer().presign(http.MethodGet, u, expires, s.time()), nil
+}
+
+func (s *S3BlobStore) objectURL(key string) (url.URL, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://" + s.Bucket + ".s3." + s.Region + ".amazonaws.com"
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return url.URL{}, fmt.Errorf("shim could not parse s3 endpoint %q: %w", endpoint, err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(key, "/")
+
+	return *u, nil
+}
+
+func (s *S3BlobStore) signer() sigv4Signer {
+	credentials := AWSCredentialsFromEnv
+	if s.Credentials != nil {
+		credentials = s.Credentials
+	}
+
+	return sigv4Signer{credentials: credentials(), region: s.Region, service: "s3"}
+}
+
+func (s *S3BlobStore) time() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+
+	return time.Now()
+}
@@ -2,16 +2,40 @@ package shim
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 )
 
 // Shim provides a thin layer between your traditional http.Handler based application and AWS Lambda + API Gateway.
 type Shim struct {
-	Handler http.Handler
-	Log     Log
+	Handler       http.Handler
+	Log           Log
+	EventPaths    EventPaths
+	Compression   *Compression
+	ResponseLimit *ResponseLimit
+	Offloader     *Offloader
+	AutoETag      bool
+	TrailerName   func(trailer string) string
+	// TrustedProxies is the number of proxies whose X-Forwarded-For entries are trusted, see WithTrustedProxies
+	TrustedProxies int
+	// PathPrefixStrategies decide which prefix is stripped from request paths, see WithPathPrefixStrategy
+	PathPrefixStrategies []PathPrefixStrategy
+	RequestIDPolicy      *RequestIDPolicy
+	// SyntheticInvocation is what Invocation reports outside of Lambda, see WithSyntheticInvocation
+	SyntheticInvocation *SyntheticInvocation
+	// ShutdownBudget is how long OnShutdown hooks get once SIGTERM was received, see WithShutdownBudget
+	ShutdownBudget time.Duration
+
+	// invocations counts the invocations handled to detect cold starts
+	invocations    atomic.Int64
+	coldStartHooks []func(ctx context.Context)
+	shutdownHooks  []func(ctx context.Context)
 }
 
 // New returns an initialized Shim with the provided http.Handler. If no http.Handler is provided New will use http.DefaultServiceMux
@@ -22,6 +46,10 @@ func New(h http.Handler, options ...func(*Shim)) *Shim {
 
 	s := &Shim{
 		Handler: h,
+		ResponseLimit: &ResponseLimit{
+			MaxSize: DefaultResponseLimit,
+			Policy:  RejectOverflow(http.StatusBadGateway),
+		},
 	}
 
 	for _, option := range options {
@@ -48,23 +76,80 @@ func SetDebugWithSlog(l slog.Logger) func(*Shim) {
 // Handle converts an APIGatewayProxyRequest converts an APIGatewayProxyRequest into an http.Request and passes it to the given http.Handler
 // along with a ResponseWriter. The response from the handler is converted into an APIGatewayProxyResponse.
 func (s *Shim) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	s.printf("event request: %+v\n", request)
+	ctx = s.startInvocation(ctx)
+	ctx = contextWithRequestIDs(ctx, request.RequestContext.RequestID)
+	s.printf(ctx, "event request: %+v\n", request)
 
 	httpReq, err := NewHttpRequestFromAPIGatewayProxyRequest(ctx, request)
 	if err != nil {
-		s.printf("received an error while constructing http request from API Gateway request event\n")
+		s.printf(ctx, "received an error while constructing http request from API Gateway request event\n")
 		return events.APIGatewayProxyResponse{}, err
 	}
-	s.printf("http request: %+v", httpReq)
-	rw := NewResponseWriter()
+	s.trustForwardedFor(httpReq)
+	httpReq = s.applyRequestIDPolicy(httpReq)
+	httpReq = s.stripPathPrefix(httpReq, request.RequestContext.Stage)
+	s.printf(ctx, "http request: %+v", httpReq)
 
-	s.printf("calling ServeHTTP on shim handler\n")
-	s.Handler.ServeHTTP(rw, httpReq)
-	s.printf("received response: %+v\n", rw)
+	return s.serveRestApi(httpReq), nil
+}
+
+// HandleRestApiRequestsWithClientCert is Handle for REST APIs with mutual TLS. The client certificate is parsed into
+// req.TLS.PeerCertificates, see APIGatewayProxyRequestWithClientCert.
+func (s *Shim) HandleRestApiRequestsWithClientCert(ctx context.Context, request APIGatewayProxyRequestWithClientCert) (events.APIGatewayProxyResponse, error) {
+	ctx = s.startInvocation(ctx)
+	ctx = contextWithRequestIDs(ctx, request.RequestContext.RequestID)
+	s.printf(ctx, "event request: %+v\n", request)
+
+	httpReq, err := NewHttpRequestFromAPIGatewayProxyRequestWithClientCert(ctx, request)
+	if err != nil {
+		s.printf(ctx, "received error while converting API Gateway request event with client certificate into http request: %v\n", err)
+		return events.APIGatewayProxyResponse{}, err
+	}
+	s.trustForwardedFor(httpReq)
+	httpReq = s.applyRequestIDPolicy(httpReq)
+	httpReq = s.stripPathPrefix(httpReq, request.RequestContext.Stage)
+	s.printf(ctx, "http request: %+v", httpReq)
+
+	return s.serveRestApi(httpReq), nil
+}
+
+// serveRestApi passes httpReq to the http.Handler and converts the response into an APIGatewayProxyResponse
+func (s *Shim) serveRestApi(httpReq *http.Request) events.APIGatewayProxyResponse {
+	rw := s.serve(httpReq, restBodyIsBase64)
+	defer releaseResponseWriter(rw)
 
 	resp := NewAPIGatewayProxyResponse(rw)
-	s.printf("api gateway proxy response: %+v\n", resp)
-	return resp, nil
+	s.printf(httpReq.Context(), "api gateway proxy response: %+v\n", resp)
+	return resp
+}
+
+// serve passes httpReq to the http.Handler and runs the buffered response through the steps every API Gateway response
+// shares. isBase64 reports whether the event response will carry the body base64 encoded. The caller builds its event
+// response from the returned ResponseWriter and must release it with releaseResponseWriter.
+func (s *Shim) serve(httpReq *http.Request, isBase64 func(h http.Header, body []byte) bool) *ResponseWriter {
+	rw := acquireResponseWriter(httpReq.Context())
+
+	s.printf(httpReq.Context(), "calling ServeHTTP on shim handler\n")
+	s.Handler.ServeHTTP(rw, httpReq)
+	s.printf(httpReq.Context(), "received response: %+v\n", rw)
+
+	s.finishHeaders(httpReq, rw)
+	// Compressing first means bodies that compress below the threshold aren't offloaded
+	s.compress(httpReq, rw)
+	s.offload(httpReq, rw)
+	stripBody(httpReq, rw)
+	s.enforceLimit(httpReq, rw, isBase64(rw.Headers, rw.Body.Bytes()))
+	// Echoed last so an overflow policy that replaces the response can't drop it
+	s.echoRequestID(httpReq, rw.Headers)
+
+	return rw
+}
+
+// finishHeaders applies the response header steps that don't depend on where the response is sent
+func (s *Shim) finishHeaders(httpReq *http.Request, rw *ResponseWriter) {
+	s.foldTrailers(rw)
+	s.dropConnectionHeaders(httpReq, rw)
+	s.applyETag(httpReq, rw)
 }
 
 // HandleRestApiRequests converts an APIGatewayProxyRequest into an http.Request and passes it to the http.Handler. Http responses are converted
@@ -76,29 +161,415 @@ func (s *Shim) HandleRestApiRequests(ctx context.Context, request events.APIGate
 // HandleHttpApiRequests converts an APIGatewayV2HTTPRequest into an http.Request and passes it to the http.Handler. Http responses are converted
 // into APIGatewayV2HTTPResponse
 func (s *Shim) HandleHttpApiRequests(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-	s.printf("shim received event request: %+v", request)
+	ctx = s.startInvocation(ctx)
+	ctx = contextWithRequestIDs(ctx, request.RequestContext.RequestID)
+	s.printf(ctx, "shim received event request: %+v", request)
 
 	httpReq, err := NewHttpRequestFromAPIGatewayV2HTTPRequest(ctx, request)
 	if err != nil {
-		s.printf("received error while converting APIGatewayV2HTTPRequest into http request: %v\n", err)
+		s.printf(ctx, "received error while converting APIGatewayV2HTTPRequest into http request: %v\n", err)
 		return events.APIGatewayV2HTTPResponse{}, err
 	}
+	s.trustForwardedFor(httpReq)
+	httpReq = s.applyRequestIDPolicy(httpReq)
+	httpReq = s.stripPathPrefix(httpReq, request.RequestContext.Stage)
 
-	s.printf("generated http request: %+v\n", httpReq)
+	s.printf(ctx, "generated http request: %+v\n", httpReq)
 
-	rw := NewResponseWriter()
-	s.printf("calling ServeHTTP on shim handler\n")
-	s.Handler.ServeHTTP(rw, httpReq)
-	s.printf("received response: %+v\n", rw)
+	rw := s.serve(httpReq, httpApiBodyIsBase64)
+	defer releaseResponseWriter(rw)
 
 	resp := NewApiGatewayV2HttpResponse(rw)
-	s.printf("api gateway v2 http response: %+v\n", resp)
+	s.printf(ctx, "api gateway v2 http response: %+v\n", resp)
+
+	return resp, nil
+}
+
+// HandleFunctionURLStreamingRequests converts a Lambda Function URL event into an http.Request and streams the response
+// of the http.Handler back to the client. The Function URL must use the RESPONSE_STREAM invoke mode and the function
+// must run on the provided.al2 or provided.al2023 runtime or be built with the lambda.norpc tag. The response is
+// returned as soon as the handler writes its headers, and every Write is sent to the client as it happens.
+// Compression, offloading and the response limit don't apply to streamed responses.
+func (s *Shim) HandleFunctionURLStreamingRequests(ctx context.Context, request events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+	ctx = s.startInvocation(ctx)
+	ctx = contextWithRequestIDs(ctx, request.RequestContext.RequestID)
+	s.printf(ctx, "shim received function url event request: %+v", request)
+
+	httpReq, err := NewHttpRequestFromLambdaFunctionURLRequest(ctx, request)
+	if err != nil {
+		s.printf(ctx, "received error while converting LambdaFunctionURLRequest into http request: %v\n", err)
+		return nil, err
+	}
+	s.trustForwardedFor(httpReq)
+	httpReq = s.applyRequestIDPolicy(httpReq)
+	httpReq = s.stripPathPrefix(httpReq, "$default")
+
+	s.printf(ctx, "generated http request: %+v\n", httpReq)
+
+	// Headers can't be changed once streaming started, so the request ID is echoed up front and the handler may replace it
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.echoRequestID(r, w.Header())
+		s.Handler.ServeHTTP(w, r)
+	})
+
+	s.printf(ctx, "calling ServeHTTP on shim handler\n")
+	resp, err := serveStreaming(handler, httpReq)
+	if err != nil {
+		s.printf(ctx, "streaming handler did not write a response before the invocation ended: %v\n", err)
+		return nil, err
+	}
+	s.printf(ctx, "function url streaming response: %v %+v\n", resp.StatusCode, resp.Headers)
+
+	return resp, nil
+}
+
+// HandleHttpApiV1Requests converts an HTTP API event using payload format version 1.0 into an http.Request and passes it
+// to the http.Handler. Http responses are converted into the APIGatewayProxyResponse shape HTTP APIs accept for 1.0.
+func (s *Shim) HandleHttpApiV1Requests(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	ctx = s.startInvocation(ctx)
+	ctx = contextWithRequestIDs(ctx, request.RequestContext.RequestID)
+	s.printf(ctx, "shim received http api v1 event request: %+v", request)
+
+	httpReq, err := NewHttpRequestFromHttpApiV1Request(ctx, request)
+	if err != nil {
+		s.printf(ctx, "received error while converting http api v1 request into http request: %v\n", err)
+		return events.APIGatewayProxyResponse{}, err
+	}
+	s.trustForwardedFor(httpReq)
+	httpReq = s.applyRequestIDPolicy(httpReq)
+	httpReq = s.stripPathPrefix(httpReq, request.RequestContext.Stage)
+
+	s.printf(ctx, "generated http request: %+v\n", httpReq)
+
+	rw := s.serve(httpReq, httpApiBodyIsBase64)
+	defer releaseResponseWriter(rw)
+
+	resp := NewHttpApiV1Response(rw)
+	s.printf(ctx, "http api v1 response: %+v\n", resp)
+
+	return resp, nil
+}
+
+// HandleHttpApiRequestsAnyVersion accepts HTTP API events of either payload format version and dispatches them to
+// HandleHttpApiV1Requests or HandleHttpApiRequests, which lets an integration switch formats without redeploying.
+func (s *Shim) HandleHttpApiRequestsAnyVersion(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	ctx = s.startInvocation(ctx)
+	var v struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, fmt.Errorf("shim could not read payload format version: %w", err)
+	}
+
+	if v.Version == "1.0" {
+		var request events.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return nil, fmt.Errorf("shim could not decode http api v1 event: %w", err)
+		}
+		return s.HandleHttpApiV1Requests(ctx, request)
+	}
+
+	var request events.APIGatewayV2HTTPRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, fmt.Errorf("shim could not decode http api v2 event: %w", err)
+	}
+	return s.HandleHttpApiRequests(ctx, request)
+}
+
+// HandleWebsocketRequests converts an APIGatewayWebsocketProxyRequest into an http.Request and passes it to the http.Handler.
+// Each route key is served from its own path, see WebsocketRoutePath. Http responses are converted into APIGatewayProxyResponse.
+func (s *Shim) HandleWebsocketRequests(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	ctx = s.startInvocation(ctx)
+	ctx = contextWithRequestIDs(ctx, request.RequestContext.RequestID)
+	s.printf(ctx, "shim received websocket event request: %+v", request)
+
+	httpReq, err := NewHttpRequestFromAPIGatewayWebsocketProxyRequest(ctx, request)
+	if err != nil {
+		s.printf(ctx, "received error while converting APIGatewayWebsocketProxyRequest into http request: %v\n", err)
+		return events.APIGatewayProxyResponse{}, err
+	}
+	httpReq = s.applyRequestIDPolicy(httpReq)
+
+	s.printf(ctx, "generated http request: %+v\n", httpReq)
+
+	rw := acquireResponseWriter(httpReq.Context())
+	defer releaseResponseWriter(rw)
+	s.printf(ctx, "calling ServeHTTP on shim handler\n")
+	s.Handler.ServeHTTP(rw, httpReq)
+	s.printf(ctx, "received response: %+v\n", rw)
+	s.echoRequestID(httpReq, rw.Headers)
+
+	// Lifecycle handlers often don't write anything, API Gateway needs a status code to accept the connection
+	if rw.Code == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	resp := NewAPIGatewayProxyResponse(rw)
+	s.printf(ctx, "api gateway websocket response: %+v\n", resp)
 
 	return resp, nil
 }
 
-func (s *Shim) printf(format string, v ...interface{}) {
+// HandleCloudFrontRequests converts a Lambda@Edge viewer-request or origin-request CloudFrontEvent into an http.Request
+// and passes it to the http.Handler. If the handler calls Continue the request is forwarded to the origin, otherwise
+// the response is generated at the edge.
+func (s *Shim) HandleCloudFrontRequests(ctx context.Context, event CloudFrontEvent) (CloudFrontResult, error) {
+	ctx = s.startInvocation(ctx)
+	s.printf(ctx, "shim received cloudfront event: %+v", event)
+
+	httpReq, err := NewHttpRequestFromCloudFrontEvent(ctx, event)
+	if err != nil {
+		s.printf(ctx, "received error while converting CloudFrontEvent into http request: %v\n", err)
+		return CloudFrontResult{}, err
+	}
+
+	s.printf(ctx, "generated http request: %+v\n", httpReq)
+
+	rw := acquireResponseWriter(httpReq.Context())
+	defer releaseResponseWriter(rw)
+	s.printf(ctx, "calling ServeHTTP on shim handler\n")
+	s.Handler.ServeHTTP(rw, httpReq)
+
+	if state := httpReq.Context().Value(cloudFrontContextKey{}).(*cloudFrontState); state.continued != nil {
+		cfReq, err := NewCloudFrontRequest(state.continued)
+		if err != nil {
+			s.printf(ctx, "received error while converting http request into CloudFrontRequest: %v\n", err)
+			return CloudFrontResult{}, err
+		}
+
+		s.printf(ctx, "forwarding cloudfront request: %+v\n", cfReq)
+		return CloudFrontResult{Request: &cfReq}, nil
+	}
+
+	s.printf(ctx, "received response: %+v\n", rw)
+
+	s.finishHeaders(httpReq, rw)
+	stripBody(httpReq, rw)
+	s.echoRequestID(httpReq, rw.Headers)
+
+	resp := NewCloudFrontResponse(rw)
+	s.printf(ctx, "cloudfront response: %+v\n", resp)
+
+	return CloudFrontResult{Response: &resp}, nil
+}
+
+// HandleAuthorizerRequests converts a REQUEST authorizer event into an http.Request and passes it to the http.Handler.
+// The handler's decision, see Authorizer, is converted into an APIGatewayCustomAuthorizerResponse.
+func (s *Shim) HandleAuthorizerRequests(ctx context.Context, request events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	ctx = s.startInvocation(ctx)
+	ctx = contextWithRequestIDs(ctx, request.RequestContext.RequestID)
+	s.printf(ctx, "shim received authorizer event request: %+v", request)
+
+	httpReq, a, err := NewHttpRequestFromAPIGatewayCustomAuthorizerRequestTypeRequest(ctx, request)
+	if err != nil {
+		s.printf(ctx, "received error while converting APIGatewayCustomAuthorizerRequestTypeRequest into http request: %v\n", err)
+		return events.APIGatewayCustomAuthorizerResponse{}, err
+	}
+
+	return s.authorize(httpReq, a, request.MethodArn)
+}
+
+// HandleTokenAuthorizerRequests converts a TOKEN authorizer event into an http.Request with the token in the Authorization
+// header and passes it to the http.Handler. The handler's decision, see Authorizer, is converted into an
+// APIGatewayCustomAuthorizerResponse.
+func (s *Shim) HandleTokenAuthorizerRequests(ctx context.Context, request events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	ctx = s.startInvocation(ctx)
+	s.printf(ctx, "shim received token authorizer event request: %+v", request)
+
+	httpReq, a, err := NewHttpRequestFromAPIGatewayCustomAuthorizerRequest(ctx, request)
+	if err != nil {
+		s.printf(ctx, "received error while converting APIGatewayCustomAuthorizerRequest into http request: %v\n", err)
+		return events.APIGatewayCustomAuthorizerResponse{}, err
+	}
+
+	return s.authorize(httpReq, a, request.MethodArn)
+}
+
+// HandleHttpApiAuthorizerRequests converts an HTTP API authorizer event into an http.Request and passes it to the
+// http.Handler. The handler's decision, see Authorizer, is converted into an APIGatewayV2CustomAuthorizerSimpleResponse.
+func (s *Shim) HandleHttpApiAuthorizerRequests(ctx context.Context, request events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	ctx = s.startInvocation(ctx)
+	ctx = contextWithRequestIDs(ctx, request.RequestContext.RequestID)
+	s.printf(ctx, "shim received http api authorizer event request: %+v", request)
+
+	httpReq, a, err := NewHttpRequestFromAPIGatewayV2CustomAuthorizerV2Request(ctx, request)
+	if err != nil {
+		s.printf(ctx, "received error while converting APIGatewayV2CustomAuthorizerV2Request into http request: %v\n", err)
+		return events.APIGatewayV2CustomAuthorizerSimpleResponse{}, err
+	}
+
+	s.printf(ctx, "generated http request: %+v\n", httpReq)
+
+	rw := acquireResponseWriter(httpReq.Context())
+	defer releaseResponseWriter(rw)
+	s.printf(ctx, "calling ServeHTTP on shim handler\n")
+	s.Handler.ServeHTTP(rw, httpReq)
+	s.printf(ctx, "received response: %+v\n", rw)
+
+	resp := NewAPIGatewayV2CustomAuthorizerSimpleResponse(rw, a)
+	s.printf(ctx, "api gateway v2 authorizer response: %+v\n", resp)
+
+	return resp, nil
+}
+
+// HandleSQSEvents sends every message of an SQSEvent to the SQS path as a POST request. Messages the handler does not
+// answer with a 2xx are reported as batch item failures, which requires ReportBatchItemFailures on the event source
+// mapping.
+func (s *Shim) HandleSQSEvents(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	ctx = s.startInvocation(ctx)
+	paths := s.EventPaths.withDefaults()
+	resp := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+
+	for _, record := range event.Records {
+		path := expandEventPath(paths.SQS, map[string]string{"queue": arnResourceName(record.EventSourceARN)})
+
+		code, err := s.serveEventRecord(ctx, path, "aws:sqs", record)
+		if err != nil {
+			return events.SQSEventResponse{}, err
+		}
+
+		if !isSuccess(code) {
+			s.printf(ctx, "sqs message %v failed with status code %v\n", record.MessageId, code)
+			resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+		}
+	}
+
+	return resp, nil
+}
+
+// HandleSNSEvents sends every record of an SNSEvent to the SNS path as a POST request. An error is returned if the
+// handler does not answer a record with a 2xx so that Lambda retries the invocation.
+func (s *Shim) HandleSNSEvents(ctx context.Context, event events.SNSEvent) error {
+	ctx = s.startInvocation(ctx)
+	paths := s.EventPaths.withDefaults()
+
+	for _, record := range event.Records {
+		path := expandEventPath(paths.SNS, map[string]string{"topic": arnResourceName(record.SNS.TopicArn)})
+
+		code, err := s.serveEventRecord(ctx, path, "aws:sns", record)
+		if err != nil {
+			return err
+		}
+
+		if !isSuccess(code) {
+			return fmt.Errorf("shim: sns message %v failed with status code %v", record.SNS.MessageID, code)
+		}
+	}
+
+	return nil
+}
+
+// HandleEventBridgeEvents sends an EventBridge event to the EventBridge path as a POST request, or to the schedule path
+// if it was produced by a schedule rule. An error is returned if the handler does not answer with a 2xx.
+func (s *Shim) HandleEventBridgeEvents(ctx context.Context, event events.EventBridgeEvent) error {
+	ctx = s.startInvocation(ctx)
+	paths := s.EventPaths.withDefaults()
+
+	var path string
+	if event.DetailType == scheduledEventDetailType && len(event.Resources) > 0 {
+		path = expandEventPath(paths.Schedule, map[string]string{"rule": arnResourceName(event.Resources[0])})
+	} else {
+		path = expandEventPath(paths.EventBridge, map[string]string{"source": event.Source, "detail-type": event.DetailType})
+	}
+
+	code, err := s.serveEventRecord(ctx, path, "aws:events", event)
+	if err != nil {
+		return err
+	}
+
+	if !isSuccess(code) {
+		return fmt.Errorf("shim: eventbridge event %v failed with status code %v", event.ID, code)
+	}
+
+	return nil
+}
+
+func (s *Shim) authorize(httpReq *http.Request, a *Authorization, methodArn string) (events.APIGatewayCustomAuthorizerResponse, error) {
+	s.printf(httpReq.Context(), "generated http request: %+v\n", httpReq)
+
+	rw := acquireResponseWriter(httpReq.Context())
+	defer releaseResponseWriter(rw)
+	s.printf(httpReq.Context(), "calling ServeHTTP on shim handler\n")
+	s.Handler.ServeHTTP(rw, httpReq)
+	s.printf(httpReq.Context(), "received response: %+v\n", rw)
+
+	resp, err := NewAPIGatewayCustomAuthorizerResponse(rw, a, methodArn)
+	if err != nil {
+		s.printf(httpReq.Context(), "handler rejected authorizer request: %v\n", err)
+		return resp, err
+	}
+
+	s.printf(httpReq.Context(), "api gateway authorizer response: %+v\n", resp)
+	return resp, nil
+}
+
+func (s *Shim) serveEventRecord(ctx context.Context, path, source string, record interface{}) (int, error) {
+	httpReq, err := NewHttpRequestFromEventRecord(ctx, path, source, record)
+	if err != nil {
+		s.printf(ctx, "received error while converting %v record into http request: %v\n", source, err)
+		return 0, err
+	}
+
+	s.printf(ctx, "generated http request: %+v\n", httpReq)
+
+	rw := acquireResponseWriter(httpReq.Context())
+	defer releaseResponseWriter(rw)
+	s.printf(ctx, "calling ServeHTTP on shim handler\n")
+	s.Handler.ServeHTTP(rw, httpReq)
+	s.printf(ctx, "received response: %+v\n", rw)
+
+	// Handlers that don't write anything succeeded as far as net/http is concerned
+	if rw.Code == 0 {
+		return http.StatusOK, nil
+	}
+
+	return rw.Code, nil
+}
+
+// offload moves the response body to the Offloader's BlobStore if it is over the threshold. The body is left as is if
+// offloading fails.
+func (s *Shim) offload(req *http.Request, rw *ResponseWriter) {
+	if s.Offloader == nil {
+		return
+	}
+
+	offloaded, err := s.Offloader.offload(req, rw)
+	if err != nil {
+		s.printf(req.Context(), "could not offload response, sending it inline: %v\n", err)
+		return
+	}
+
+	if offloaded {
+		s.printf(req.Context(), "offloaded response body, redirecting to %v\n", rw.Headers.Get(httpHeaderLocation))
+	}
+}
+
+// compress compresses the response body if WithCompression is enabled. The body is left as is if compression fails.
+func (s *Shim) compress(req *http.Request, rw *ResponseWriter) {
+	if s.Compression == nil {
+		return
+	}
+
+	if err := s.Compression.compress(req, rw); err != nil {
+		s.printf(req.Context(), "could not compress response, sending it uncompressed: %v\n", err)
+	}
+}
+
+// enforceLimit applies the response limit policy if the response is too large to be returned from Lambda
+func (s *Shim) enforceLimit(req *http.Request, rw *ResponseWriter, base64Encoded bool) {
+	if s.ResponseLimit == nil {
+		return
+	}
+
+	if s.ResponseLimit.enforce(req, rw, base64Encoded) {
+		s.printf(req.Context(), "response exceeded the limit of %d bytes, applied overflow policy\n", s.ResponseLimit.MaxSize)
+	}
+}
+
+// printf logs to the debug logger, prefixed with the request IDs known in ctx
+func (s *Shim) printf(ctx context.Context, format string, v ...interface{}) {
 	if s.Log != nil {
-		s.Log.Printf(format, v...)
+		// The prefix may hold an ID the client sent, so it must not become part of the format
+		s.Log.Printf("%s"+format, append([]interface{}{logPrefix(ctx)}, v...)...)
 	}
 }
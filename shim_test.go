@@ -1,6 +1,7 @@
 package shim
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"fmt"
@@ -217,3 +218,64 @@ func TestHandleHttpApiRequests(t *testing.T) {
 		t.Errorf("expected body '%s', got '%s'", body, string(resp.Body))
 	}
 }
+
+var benchmarkBodySizes = []int{1 << 10, 64 << 10, 1 << 20}
+
+func benchmarkHandler(body []byte, contentType string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(body)
+	})
+}
+
+func BenchmarkHandle(b *testing.B) {
+	for _, size := range benchmarkBodySizes {
+		for _, contentType := range []string{"application/json", "application/octet-stream"} {
+			s := New(benchmarkHandler(bytes.Repeat([]byte("a"), size), contentType))
+			event := events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodGet,
+				Path:       "/",
+				Headers:    map[string]string{"Host": "example.com", "Accept": "*/*"},
+			}
+
+			b.Run(byteSize(size)+"/"+contentType, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := s.Handle(context.Background(), event); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkHandleHttpApiRequests(b *testing.B) {
+	for _, size := range benchmarkBodySizes {
+		for _, body := range [][]byte{bytes.Repeat([]byte("a"), size), bytes.Repeat([]byte{0xff}, size)} {
+			s := New(benchmarkHandler(body, "application/octet-stream"))
+			event := events.APIGatewayV2HTTPRequest{
+				RawPath: "/",
+				Headers: map[string]string{"host": "example.com", "accept": "*/*"},
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+				},
+			}
+
+			kind := "text"
+			if body[0] == 0xff {
+				kind = "binary"
+			}
+
+			b.Run(byteSize(size)+"/"+kind, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := s.HandleHttpApiRequests(context.Background(), event); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package shim
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// DefaultShutdownBudget is how long OnShutdown hooks get unless WithShutdownBudget says otherwise. Lambda sends SIGKILL
+// 500ms after SIGTERM when only internal extensions are registered.
+const DefaultShutdownBudget = 500 * time.Millisecond
+
+// WithShutdownBudget is an option function to set how long OnShutdown hooks get once SIGTERM was received. Use a longer
+// budget if the function also has external extensions, which give the shutdown phase up to 2s.
+func WithShutdownBudget(d time.Duration) func(*Shim) {
+	return func(s *Shim) {
+		s.ShutdownBudget = d
+	}
+}
+
+// OnShutdown registers a hook that runs when the execution environment shuts down, to flush metrics, traces or logs
+// that are still buffered. The context is canceled when the shutdown budget runs out. Hooks run in the order they were
+// registered and only run in Lambda if EnableSIGTERM was passed to lambda.StartWithOptions.
+func (s *Shim) OnShutdown(hook func(ctx context.Context)) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// EnableSIGTERM returns a lambda.Option that registers an internal extension, so Lambda sends SIGTERM before it shuts
+// down the execution environment, and runs the OnShutdown hooks when it does:
+//
+//	lambda.StartWithOptions(s.HandleHttpApiRequests, s.EnableSIGTERM())
+func (s *Shim) EnableSIGTERM() lambda.Option {
+	return lambda.WithEnableSIGTERM(s.sigterm)
+}
+
+// Shutdown runs the OnShutdown hooks with ctx. A hook that panics doesn't keep the others from running. Tests and
+// local servers can call it to drive the same shutdown sequence SIGTERM triggers in Lambda.
+func (s *Shim) Shutdown(ctx context.Context) {
+	s.printf(ctx, "running %v shutdown hooks\n", len(s.shutdownHooks))
+
+	for i, hook := range s.shutdownHooks {
+		s.runShutdownHook(ctx, i, hook)
+	}
+
+	if err := ctx.Err(); err != nil {
+		s.printf(ctx, "shutdown hooks did not finish within the budget: %v\n", err)
+	}
+}
+
+func (s *Shim) runShutdownHook(ctx context.Context, i int, hook func(ctx context.Context)) {
+	defer func() {
+		if v := recover(); v != nil {
+			s.printf(ctx, "shim recovered from panic in shutdown hook %v: %v\n", i, v)
+		}
+	}()
+
+	hook(ctx)
+}
+
+// sigterm runs the OnShutdown hooks with the remaining shutdown budget
+func (s *Shim) sigterm() {
+	budget := s.ShutdownBudget
+	if budget <= 0 {
+		budget = DefaultShutdownBudget
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+
+	s.Shutdown(ctx)
+}
@@ -0,0 +1,77 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShutdownHooks(t *testing.T) {
+	s := New(http.NotFoundHandler(), WithShutdownBudget(time.Second))
+
+	var calls []string
+	var remaining time.Duration
+	s.OnShutdown(func(ctx context.Context) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Error("expected the shutdown context to have a deadline")
+		}
+		remaining = time.Until(deadline)
+		calls = append(calls, "metrics")
+	})
+	s.OnShutdown(func(ctx context.Context) {
+		panic("exporter is broken")
+	})
+	s.OnShutdown(func(ctx context.Context) {
+		calls = append(calls, "traces")
+	})
+
+	// Drive the same sequence SIGTERM triggers in Lambda
+	s.sigterm()
+
+	if len(calls) != 2 || calls[0] != "metrics" || calls[1] != "traces" {
+		t.Errorf("expected every hook to run in order despite the panic but got %v", calls)
+	}
+	if remaining <= 900*time.Millisecond || remaining > time.Second {
+		t.Errorf("expected the hooks to get the shutdown budget but got %v", remaining)
+	}
+}
+
+func TestShutdownDefaultBudget(t *testing.T) {
+	s := New(http.NotFoundHandler())
+
+	var canceled bool
+	s.OnShutdown(func(ctx context.Context) {
+		<-ctx.Done()
+		canceled = true
+	})
+
+	start := time.Now()
+	s.sigterm()
+
+	if !canceled {
+		t.Error("expected the shutdown context to be canceled")
+	}
+	if elapsed := time.Since(start); elapsed < DefaultShutdownBudget || elapsed > 2*DefaultShutdownBudget {
+		t.Errorf("expected the hooks to be canceled after %v but took %v", DefaultShutdownBudget, elapsed)
+	}
+}
+
+func TestShutdownWithContext(t *testing.T) {
+	s := New(http.NotFoundHandler())
+
+	flushed := false
+	s.OnShutdown(func(ctx context.Context) {
+		flushed = true
+	})
+
+	s.Shutdown(context.Background())
+
+	if !flushed {
+		t.Error("expected Shutdown to run the hooks")
+	}
+	if s.EnableSIGTERM() == nil {
+		t.Error("expected a lambda.Option")
+	}
+}
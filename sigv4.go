@@ -0,0 +1,192 @@
+package shim
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A minimal AWS Signature Version 4 implementation so shim can talk to S3 without pulling in the AWS SDK.
+// See https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+
+const (
+	sigv4Algorithm       = "AWS4-HMAC-SHA256"
+	sigv4TimeFormat      = "20060102T150405Z"
+	sigv4DateFormat      = "20060102"
+	sigv4UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// AWSCredentials are the keys used to sign requests to AWS
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSCredentialsFromEnv reads credentials from the environment variables Lambda sets for the execution role
+func AWSCredentialsFromEnv() AWSCredentials {
+	return AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+type sigv4Signer struct {
+	credentials AWSCredentials
+	region      string
+	service     string
+}
+
+// sign adds the x-amz-* and Authorization headers to req. payloadHash is the hex encoded SHA-256 of the body.
+func (s sigv4Signer) sign(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format(sigv4TimeFormat)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, vs := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(vs, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigv4EscapePath(req.URL.Path),
+		sigv4CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := s.scope(t)
+	signature := s.signature(t, scope, canonicalRequest)
+
+	req.Header.Set("Authorization", sigv4Algorithm+" Credential="+s.credentials.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// presign returns u with a query string signature that is valid for expires. Only the host header is signed.
+func (s sigv4Signer) presign(method string, u url.URL, expires time.Duration, t time.Time) string {
+	t = t.UTC()
+	scope := s.scope(t)
+
+	q := u.Query()
+	q.Set("X-Amz-Algorithm", sigv4Algorithm)
+	q.Set("X-Amz-Credential", s.credentials.AccessKeyID+"/"+scope)
+	q.Set("X-Amz-Date", t.Format(sigv4TimeFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if s.credentials.SessionToken != "" {
+		q.Set("X-Amz-Security-Token", s.credentials.SessionToken)
+	}
+
+	canonicalQuery := sigv4CanonicalQuery(q)
+	canonicalRequest := strings.Join([]string{
+		method,
+		sigv4EscapePath(u.Path),
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		sigv4UnsignedPayload,
+	}, "\n")
+
+	u.RawQuery = canonicalQuery + "&X-Amz-Signature=" + s.signature(t, scope, canonicalRequest)
+	u.RawPath = sigv4EscapePath(u.Path)
+	return u.String()
+}
+
+func (s sigv4Signer) scope(t time.Time) string {
+	return t.Format(sigv4DateFormat) + "/" + s.region + "/" + s.service + "/aws4_request"
+}
+
+func (s sigv4Signer) signature(t time.Time, scope, canonicalRequest string) string {
+	stringToSign := sigv4Algorithm + "\n" + t.Format(sigv4TimeFormat) + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.credentials.SecretAccessKey), t.Format(sigv4DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// sigv4CanonicalQuery sorts and strictly escapes query parameters as SigV4 requires
+func sigv4CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(q))
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, sigv4Escape(k, true)+"="+sigv4Escape(v, true))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// sigv4EscapePath escapes every path segment without touching the separating slashes
+func sigv4EscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	return sigv4Escape(p, false)
+}
+
+// sigv4Escape percent-encodes everything but unreserved characters. Slashes are kept unless encodeSlash is set.
+func sigv4Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+
+		b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+
+	return b.String()
+}
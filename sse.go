@@ -0,0 +1,76 @@
+package shim
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEEvent is a single Server-Sent Event
+type SSEEvent struct {
+	// ID sets the last event ID the browser sends back when it reconnects
+	ID string
+	// Event is the event type. Browsers dispatch events without a type as "message".
+	Event string
+	// Data is the payload. Multi-line data is sent as one data field per line.
+	Data string
+	// Retry tells the browser how long to wait before reconnecting
+	Retry time.Duration
+}
+
+// SSE writes Server-Sent Events to an http.ResponseWriter and flushes each one. Behind
+// HandleFunctionURLStreamingRequests every event reaches the client as it is sent; behind buffered integrations the
+// events are collected and sent together when the handler returns.
+type SSE struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewSSE sets the event stream headers on w and returns an SSE that writes to it
+func NewSSE(w http.ResponseWriter) *SSE {
+	w.Header().Set(httpHeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	return &SSE{w: w, rc: http.NewResponseController(w)}
+}
+
+// Send writes e and flushes it to the client
+func (s *SSE) Send(e SSEEvent) error {
+	var b strings.Builder
+
+	if e.ID != "" {
+		b.WriteString("id: " + stripNewlines(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + stripNewlines(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	data := strings.ReplaceAll(e.Data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Comment writes a comment line, which clients ignore. Sending one periodically keeps idle connections open.
+func (s *SSE) Comment(text string) error {
+	return s.write(": " + stripNewlines(text) + "\n\n")
+}
+
+func (s *SSE) write(msg string) error {
+	if _, err := s.w.Write([]byte(msg)); err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
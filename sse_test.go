@@ -0,0 +1,56 @@
+package shim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestSSESend(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sse := NewSSE(rec)
+
+	sse.Send(SSEEvent{Data: "hello"})
+	sse.Send(SSEEvent{ID: "7", Event: "update", Data: "a\r\nb", Retry: 2 * time.Second})
+	sse.Comment("keep-alive")
+
+	expected := "data: hello\n\n" +
+		"id: 7\nevent: update\nretry: 2000\ndata: a\ndata: b\n\n" +
+		": keep-alive\n\n"
+	if rec.Body.String() != expected {
+		t.Errorf("expected %q but got %q", expected, rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Error("expected events to be flushed")
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected Content-Type to be text/event-stream but was %q", ct)
+	}
+}
+
+func TestSSEBufferedFallback(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sse := NewSSE(w)
+		sse.Send(SSEEvent{Data: "one"})
+		sse.Send(SSEEvent{Data: "two"})
+	})
+
+	resp, err := New(h).HandleHttpApiRequests(context.Background(), events.APIGatewayV2HTTPRequest{
+		RawPath:        "/events",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Body != "data: one\n\ndata: two\n\n" {
+		t.Errorf("expected events to be collected but got %q", resp.Body)
+	}
+	if resp.Headers["Content-Type"] != "text/event-stream" {
+		t.Errorf("expected Content-Type to be text/event-stream but was %q", resp.Headers["Content-Type"])
+	}
+}
@@ -0,0 +1,58 @@
+package shim
+
+import (
+	"net/http"
+	"strings"
+)
+
+const httpHeaderTrailer = "Trailer"
+
+// WithTrailerNaming is an option function to rename trailers when they are folded into the response headers, e.g. to
+// keep them apart from headers the handler set:
+//
+//	shim.WithTrailerNaming(func(name string) string { return "X-Trailer-" + name })
+//
+// By default trailers keep their name.
+func WithTrailerNaming(name func(trailer string) string) func(*Shim) {
+	return func(s *Shim) {
+		s.TrailerName = name
+	}
+}
+
+// foldTrailers turns trailers into regular response headers since neither API Gateway nor Lambda can send real
+// trailers. Both trailers declared in the Trailer header and those set with the http.TrailerPrefix are folded, the
+// Trailer header itself is dropped.
+func (s *Shim) foldTrailers(rw *ResponseWriter) {
+	declared := rw.Headers.Values(httpHeaderTrailer)
+	rw.Headers.Del(httpHeaderTrailer)
+
+	for _, names := range declared {
+		for _, name := range strings.Split(names, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+
+			if vs, ok := rw.Headers[name]; ok {
+				s.setTrailer(rw.Headers, name, vs)
+			}
+		}
+	}
+
+	for k, vs := range rw.Headers {
+		if name, ok := strings.CutPrefix(k, http.TrailerPrefix); ok {
+			delete(rw.Headers, k)
+			s.setTrailer(rw.Headers, http.CanonicalHeaderKey(name), vs)
+		}
+	}
+}
+
+func (s *Shim) setTrailer(h http.Header, name string, vs []string) {
+	if s.TrailerName == nil {
+		h[name] = vs
+		return
+	}
+
+	delete(h, name)
+	h[http.CanonicalHeaderKey(s.TrailerName(name))] = vs
+}
@@ -0,0 +1,96 @@
+package shim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var grpcWebHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.Header().Set("Content-Type", "application/grpc-web+proto")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "payload")
+
+	w.Header().Set("Grpc-Status", "0")
+	w.Header().Set("Grpc-Message", "OK")
+	w.Header().Set(http.TrailerPrefix+"X-Checksum", "abc")
+})
+
+func TestTrailersAreFoldedIntoHeaders(t *testing.T) {
+	resp, err := New(grpcWebHandler).HandleHttpApiRequests(context.Background(), v2Request(http.MethodPost, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"Grpc-Status":  "0",
+		"Grpc-Message": "OK",
+		"X-Checksum":   "abc",
+	}
+	for k, v := range expected {
+		if resp.Headers[k] != v {
+			t.Errorf("expected header %v to be %q but was %q", k, v, resp.Headers[k])
+		}
+	}
+
+	for k := range resp.Headers {
+		if k == "Trailer" || k == http.TrailerPrefix+"X-Checksum" {
+			t.Errorf("expected %v to be dropped", k)
+		}
+	}
+}
+
+func TestTrailerNaming(t *testing.T) {
+	s := New(grpcWebHandler, WithTrailerNaming(func(name string) string { return "X-Trailer-" + name }))
+
+	resp, err := s.Handle(context.Background(), restRequest(http.MethodPost, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Headers["X-Trailer-Grpc-Status"] != "0" || resp.Headers["X-Trailer-X-Checksum"] != "abc" {
+		t.Errorf("expected trailers to be renamed but got %+v", resp.Headers)
+	}
+	if _, ok := resp.Headers["Grpc-Status"]; ok {
+		t.Errorf("expected the original trailer name to be dropped but got %+v", resp.Headers)
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G', 0xff, 0x00})
+	})
+
+	rest, err := New(h).Handle(context.Background(), restRequest(http.MethodGet, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rest.IsBase64Encoded || rest.Headers["Content-Length"] != "6" {
+		t.Errorf("expected Content-Length of the decoded body but got %+v", rest)
+	}
+
+	v2, err := New(h).HandleHttpApiRequests(context.Background(), v2Request(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v2.IsBase64Encoded || v2.Headers["Content-Length"] != "6" {
+		t.Errorf("expected Content-Length of the decoded body but got %+v", v2)
+	}
+
+	v1, err := New(h).HandleHttpApiV1Requests(context.Background(), restRequest(http.MethodGet, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cl := v1.MultiValueHeaders["Content-Length"]; len(cl) != 1 || cl[0] != "6" {
+		t.Errorf("expected Content-Length of the decoded body but got %+v", v1)
+	}
+}
+
+func restRequest(method, path string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{HTTPMethod: method, Path: path}
+}
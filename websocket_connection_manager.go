@@ -0,0 +1,94 @@
+package shim
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrConnectionGone is returned by a ConnectionManager when the WebSocket connection no longer exists. It mirrors the
+// GoneException returned by the API Gateway @connections management API.
+var ErrConnectionGone = errors.New("shim: websocket connection is gone")
+
+// ConnectionManager posts messages back to connected WebSocket clients through the API Gateway @connections management
+// API. The apigatewaymanagementapi client from the AWS SDK is easily adapted to this interface using the endpoint
+// returned by WebsocketCallbackURL.
+type ConnectionManager interface {
+	PostToConnection(ctx context.Context, connectionID string, data []byte) error
+	DeleteConnection(ctx context.Context, connectionID string) error
+}
+
+// MemoryConnectionManager is an in-memory ConnectionManager meant to stand in for the @connections API in tests.
+// Connections must be opened with Connect before messages can be posted to them.
+type MemoryConnectionManager struct {
+	mu          sync.Mutex
+	connections map[string][][]byte
+}
+
+// NewMemoryConnectionManager returns an empty MemoryConnectionManager
+func NewMemoryConnectionManager() *MemoryConnectionManager {
+	return &MemoryConnectionManager{
+		connections: make(map[string][][]byte),
+	}
+}
+
+// Connect registers a connection so that messages can be posted to it
+func (m *MemoryConnectionManager) Connect(connectionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.connections[connectionID]; !ok {
+		m.connections[connectionID] = nil
+	}
+}
+
+// PostToConnection records data as sent to the connection. It returns ErrConnectionGone if the connection is unknown.
+func (m *MemoryConnectionManager) PostToConnection(ctx context.Context, connectionID string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msgs, ok := m.connections[connectionID]
+	if !ok {
+		return ErrConnectionGone
+	}
+
+	msg := make([]byte, len(data))
+	copy(msg, data)
+	m.connections[connectionID] = append(msgs, msg)
+
+	return nil
+}
+
+// DeleteConnection closes the connection. It returns ErrConnectionGone if the connection is unknown.
+func (m *MemoryConnectionManager) DeleteConnection(ctx context.Context, connectionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.connections[connectionID]; !ok {
+		return ErrConnectionGone
+	}
+
+	delete(m.connections, connectionID)
+	return nil
+}
+
+// Connected reports whether the connection is open
+func (m *MemoryConnectionManager) Connected(connectionID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.connections[connectionID]
+	return ok
+}
+
+// Messages returns the messages posted to the connection in the order they were sent
+func (m *MemoryConnectionManager) Messages(connectionID string) [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msgs := m.connections[connectionID]
+	out := make([][]byte, len(msgs))
+	copy(out, msgs)
+
+	return out
+}